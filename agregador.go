@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// agregador builds the lookup tables of the output (laboratories,
+// presentations, substances, therapeutic classes, the tax summary and the
+// equivalence groups) as medicines are parsed. Every table is backed by a
+// hash index, so the cost of adding a medicine does not grow with the
+// number of rows already seen.
+type agregador struct {
+	laboratorios        map[string]*CadastroLaboratorio
+	cnpjsPorNome        map[string][]string
+	apresentacoes       []ApresentacaoCatalogo
+	indiceApresentacoes map[string]int
+	substancias         map[string][]string
+	classes             map[string]string
+	resumoTributario    ResumoTributario
+	equivalencias       map[string]*grupoParcial
+	ordemEquivalencias  []string
+}
+
+func novoAgregador() *agregador {
+	return &agregador{
+		laboratorios:        make(map[string]*CadastroLaboratorio),
+		cnpjsPorNome:        make(map[string][]string),
+		indiceApresentacoes: make(map[string]int),
+		substancias:         make(map[string][]string),
+		classes:             make(map[string]string),
+		resumoTributario: ResumoTributario{
+			PorLista:       make(map[ListaPISCOFINS]int),
+			PorLaboratorio: make(map[string]map[ListaPISCOFINS]int),
+		},
+		equivalencias: make(map[string]*grupoParcial),
+	}
+}
+
+func (a *agregador) adiciona(medicamento Medicamento) {
+	cnpjLaboratorio, temCNPJ := medicamento[CNPJ].(string)
+	if temCNPJ {
+		cadastro, ok := a.laboratorios[cnpjLaboratorio]
+		if !ok {
+			cadastro = &CadastroLaboratorio{Variantes: []string{}}
+			a.laboratorios[cnpjLaboratorio] = cadastro
+		}
+		cadastro.Produtos++
+
+		if nome, ok := medicamento[Laboratorio].(string); ok {
+			if cadastro.Nome == "" {
+				cadastro.Nome = nome
+			}
+			if !slices.Contains(cadastro.Variantes, nome) {
+				cadastro.Variantes = append(cadastro.Variantes, nome)
+			}
+			chave := normalizaTexto(nome)
+			if !slices.Contains(a.cnpjsPorNome[chave], cnpjLaboratorio) {
+				a.cnpjsPorNome[chave] = append(a.cnpjsPorNome[chave], cnpjLaboratorio)
+			}
+		}
+	}
+
+	medicamento[ApresentacaoID] = nil
+	if variante, ok := medicamento[Apresentacao].(string); ok {
+		apresentacao := removeAccents(variante)
+		i, ok := a.indiceApresentacoes[apresentacao]
+		if !ok {
+			i = len(a.apresentacoes)
+			a.indiceApresentacoes[apresentacao] = i
+			a.apresentacoes = append(a.apresentacoes, ApresentacaoCatalogo{
+				ID:           i + 1,
+				Descricao:    apresentacao,
+				Variantes:    []string{},
+				CodigosGGREM: []string{},
+			})
+		}
+
+		catalogo := &a.apresentacoes[i]
+		catalogo.Ocorrencias++
+		// A presentation has only a handful of spellings, so a linear
+		// search is cheaper than another index here.
+		if !slices.Contains(catalogo.Variantes, variante) {
+			catalogo.Variantes = append(catalogo.Variantes, variante)
+		}
+		if ggrem, ok := medicamento[CodigoGGREM].(string); ok {
+			catalogo.CodigosGGREM = append(catalogo.CodigosGGREM, ggrem)
+		}
+		medicamento[ApresentacaoID] = catalogo.ID
+	}
+
+	substancias, _ := medicamento[Substancias].([]string)
+	for _, substancia := range substancias {
+		if _, ok := a.substancias[substancia]; !ok {
+			a.substancias[substancia] = []string{}
+		}
+		if medicamento[CodigoGGREM] != nil {
+			a.substancias[substancia] = append(a.substancias[substancia], medicamento[CodigoGGREM].(string))
+		}
+	}
+
+	if classe, ok := medicamento[Classificacao].(ClassificacaoTerapeutica); ok {
+		if _, ok := a.classes[classe.Codigo]; !ok {
+			a.classes[classe.Codigo] = classe.Descricao
+		}
+	}
+
+	if lista, ok := medicamento[ListaPISCOFINSCodigo].(ListaPISCOFINS); ok {
+		a.resumoTributario.PorLista[lista]++
+		if temCNPJ {
+			if a.resumoTributario.PorLaboratorio[cnpjLaboratorio] == nil {
+				a.resumoTributario.PorLaboratorio[cnpjLaboratorio] = make(map[ListaPISCOFINS]int)
+			}
+			a.resumoTributario.PorLaboratorio[cnpjLaboratorio][lista]++
+		}
+	}
+
+	a.adicionaEquivalencia(medicamento)
+}
+
+func (a *agregador) preenche(output *Output) {
+	output.Laboratorios = make(map[string]CadastroLaboratorio, len(a.laboratorios))
+	output.GruposLaboratorios = make(map[string][]string)
+	for cnpj, cadastro := range a.laboratorios {
+		output.Laboratorios[cnpj] = *cadastro
+		raiz := raizCNPJ(cnpj)
+		output.GruposLaboratorios[raiz] = append(output.GruposLaboratorios[raiz], cnpj)
+	}
+	for _, cnpjs := range output.GruposLaboratorios {
+		slices.Sort(cnpjs)
+	}
+	output.Avisos = append(output.Avisos, a.avisosLaboratorios()...)
+
+	output.Apresentacoes = a.apresentacoes
+	output.Substancias = a.substancias
+	output.ClassesTerapeuticas = a.classes
+	output.ResumoTributario = a.resumoTributario
+	output.GruposEquivalencia = a.gruposEquivalencia()
+}
+
+// avisosLaboratorios reports CNPJs registered under different names and
+// names registered under several CNPJs. Names that differ only in accents,
+// case or spacing are not considered different.
+func (a *agregador) avisosLaboratorios() []string {
+	var avisos []string
+
+	cnpjs := make([]string, 0, len(a.laboratorios))
+	for cnpj := range a.laboratorios {
+		cnpjs = append(cnpjs, cnpj)
+	}
+	sort.Strings(cnpjs)
+	for _, cnpj := range cnpjs {
+		nomes := make(map[string]bool)
+		for _, variante := range a.laboratorios[cnpj].Variantes {
+			nomes[normalizaTexto(variante)] = true
+		}
+		if len(nomes) > 1 {
+			avisos = append(avisos, fmt.Sprintf("CNPJ %s associado a nomes diferentes: '%s'", cnpj, strings.Join(a.laboratorios[cnpj].Variantes, "', '")))
+		}
+	}
+
+	nomes := make([]string, 0, len(a.cnpjsPorNome))
+	for nome := range a.cnpjsPorNome {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	for _, nome := range nomes {
+		if cnpjsNome := a.cnpjsPorNome[nome]; len(cnpjsNome) > 1 {
+			avisos = append(avisos, fmt.Sprintf("laboratório '%s' associado a vários CNPJs: %s", nome, strings.Join(cnpjsNome, ", ")))
+		}
+	}
+
+	return avisos
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgregador(t *testing.T) {
+	medicamentos := []Medicamento{
+		{
+			CNPJ:         "12.345.678/0001-90",
+			Laboratorio:  "LAB A",
+			CodigoGGREM:  "500101101111417",
+			Apresentacao: "200 MG COM REV",
+			Substancias:  []string{"IBUPROFENO"},
+		},
+		{
+			CNPJ:         "12.345.678/0001-90",
+			Laboratorio:  "LAB A LTDA",
+			CodigoGGREM:  "500101101111418",
+			Apresentacao: "200 MG COM RÉV",
+			Substancias:  []string{"IBUPROFENO", "CAFEINA"},
+		},
+		{
+			CNPJ:         "12.345.678/0002-71",
+			Laboratorio:  "Lab A",
+			CodigoGGREM:  nil,
+			Apresentacao: "SOL OR",
+			Substancias:  []string{"CAFEINA"},
+		},
+		{
+			CNPJ:         nil,
+			Laboratorio:  nil,
+			CodigoGGREM:  nil,
+			Apresentacao: nil,
+			Substancias:  []string{},
+		},
+	}
+
+	agregador := novoAgregador()
+	for _, medicamento := range medicamentos {
+		agregador.adiciona(medicamento)
+	}
+
+	var output Output
+	agregador.preenche(&output)
+
+	expectedLaboratorios := map[string]CadastroLaboratorio{
+		"12.345.678/0001-90": {Nome: "LAB A", Variantes: []string{"LAB A", "LAB A LTDA"}, Produtos: 2},
+		"12.345.678/0002-71": {Nome: "Lab A", Variantes: []string{"Lab A"}, Produtos: 1},
+	}
+	if !reflect.DeepEqual(output.Laboratorios, expectedLaboratorios) {
+		t.Errorf("esperado: %+v, obtido: %+v", expectedLaboratorios, output.Laboratorios)
+	}
+	expectedGrupos := map[string][]string{
+		"12.345.678": {"12.345.678/0001-90", "12.345.678/0002-71"},
+	}
+	if !reflect.DeepEqual(output.GruposLaboratorios, expectedGrupos) {
+		t.Errorf("esperado: %v, obtido: %v", expectedGrupos, output.GruposLaboratorios)
+	}
+	expectedAvisos := []string{
+		"CNPJ 12.345.678/0001-90 associado a nomes diferentes: 'LAB A', 'LAB A LTDA'",
+		"laboratório 'LAB A' associado a vários CNPJs: 12.345.678/0001-90, 12.345.678/0002-71",
+	}
+	if !reflect.DeepEqual(output.Avisos, expectedAvisos) {
+		t.Errorf("esperado: %v, obtido: %v", expectedAvisos, output.Avisos)
+	}
+	expectedApresentacoes := []ApresentacaoCatalogo{
+		{
+			ID:           1,
+			Descricao:    "200 MG COM REV",
+			Ocorrencias:  2,
+			Variantes:    []string{"200 MG COM REV", "200 MG COM RÉV"},
+			CodigosGGREM: []string{"500101101111417", "500101101111418"},
+		},
+		{ID: 2, Descricao: "SOL OR", Ocorrencias: 1, Variantes: []string{"SOL OR"}, CodigosGGREM: []string{}},
+	}
+	if !reflect.DeepEqual(output.Apresentacoes, expectedApresentacoes) {
+		t.Errorf("esperado: %+v, obtido: %+v", expectedApresentacoes, output.Apresentacoes)
+	}
+	for i, expected := range []any{1, 1, 2, nil} {
+		if id := medicamentos[i][ApresentacaoID]; id != expected {
+			t.Errorf("medicamento %d: esperado: %v, obtido: %v", i, expected, id)
+		}
+	}
+	expectedSubstancias := map[string][]string{
+		"IBUPROFENO": {"500101101111417", "500101101111418"},
+		"CAFEINA":    {"500101101111418"},
+	}
+	if !reflect.DeepEqual(output.Substancias, expectedSubstancias) {
+		t.Errorf("esperado: %v, obtido: %v", expectedSubstancias, output.Substancias)
+	}
+}
+
+// linhasSinteticas builds a spreadsheet with n medicines whose lookup tables
+// grow with n, as in the real CMED table.
+func linhasSinteticas(n int) [][]string {
+	rows := [][]string{{"Observação"}, cabecalho}
+	for i := 0; i < n; i++ {
+		row := make([]string, len(cabecalho))
+		row[0] = fmt.Sprintf("SUBSTÂNCIA %d;SUBSTÂNCIA %d", i%5000, i%7)
+		row[1] = fmt.Sprintf("%02d.345.678/0001-%02d", i%97, i%89)
+		row[2] = fmt.Sprintf("LABORATÓRIO %d", i%97)
+		row[3] = fmt.Sprintf("%015d", i)
+		row[9] = fmt.Sprintf("%d MG COM REV CT BL AL X %d", i%200, i%50)
+		row[10] = "M1A - ANTI-REUMÁTICOS NÃO ESTEROIDES"
+		row[11] = "Genérico"
+		row[12] = "Regulado"
+		for j := 13; j < 65; j++ {
+			row[j] = fmt.Sprintf("%d,%02d", i%1000, j)
+		}
+		row[65] = "Não"
+		row[70] = "Positiva"
+		row[72] = "Tarja Vermelha"
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func BenchmarkProcessRows(b *testing.B) {
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	for _, n := range []int{25000, 50000, 100000} {
+		rows := linhasSinteticas(n)
+		b.Run(fmt.Sprintf("linhas=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := processRows(rows, data, data, OpcoesProcessamento{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// A constant ns/linha across sizes shows linear scaling.
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/linha")
+		})
+	}
+}
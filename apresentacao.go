@@ -0,0 +1,170 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DetalhesApresentacao is the structure read from an APRESENTAÇÃO value such
+// as "50 MG/ML SOL OR CT FR VD AMB X 100 ML": the concentration, the
+// pharmaceutical form and the quantity in the pack.
+type DetalhesApresentacao struct {
+	Concentracao string `json:"concentracao" xml:"Concentracao"`
+	Forma        string `json:"forma" xml:"Forma"`
+	// Quantidade is the number of units (UN), millilitres (ML) or grams (G)
+	// in the pack, or nil when it cannot be determined.
+	Quantidade *float64 `json:"quantidade" xml:"Quantidade,omitempty"`
+	Unidade    string   `json:"unidade" xml:"Unidade"`
+}
+
+var (
+	// The spreadsheet is not consistent about spaces around numbers and
+	// signs, as in "500MG" or "(500+125) MG".
+	numeroLetraRegex = regexp.MustCompile(`(\d)([A-Z%])`)
+	xNumeroRegex     = regexp.MustCompile(`\bX(\d)`)
+	sinaisRegex      = regexp.MustCompile(`\s*([+()])\s*`)
+
+	tokenConcentracaoRegex = regexp.MustCompile(`^(\(|\)|\+|[\d.,]+|(MCG|MG|G|KG|UI|U|MEQ|MMOL|ML|L|%)(/[\d.,]*(MCG|MG|G|KG|UI|ML|L|DOSE|GOTA|H|24H|ACION|JATO|CAPS|COM)?)?)$`)
+	quantidadeRegex        = regexp.MustCompile(`^([\d.]+)(ML|L|G|KG|MG|MCG)?$`)
+)
+
+// Tokens that start the description of the packaging, which follows the
+// pharmaceutical form.
+var tokensEmbalagem = map[string]bool{
+	"CT": true, "CX": true, "EMB": true, "FR": true, "BL": true, "ENV": true, "BG": true, "AMP": true,
+	"FA": true, "SER": true, "POTE": true, "STRIP": true, "CARP": true, "TB": true, "LAT": true,
+	"SACHE": true, "BOLSA": true, "DISP": true, "X": true, "+": true,
+}
+
+// fatoresUnidade converts the quantity of a pack to millilitres or grams.
+var fatoresUnidade = map[string]struct {
+	unidade string
+	fator   float64
+}{
+	"":    {"UN", 1},
+	"ML":  {"ML", 1},
+	"L":   {"ML", 1000},
+	"G":   {"G", 1},
+	"KG":  {"G", 1000},
+	"MG":  {"G", 0.001},
+	"MCG": {"G", 0.000001},
+}
+
+func parseNumeroApresentacao(s string) (float64, bool) {
+	valor, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return valor, err == nil
+}
+
+// parseApresentacao reads the concentration, the form and the quantity of
+// an APRESENTAÇÃO value. The concentration is made of the leading numbers
+// and units, the form of the words up to the packaging, and the quantity
+// of the "X n [unit]" factors multiplied together and by the counts of
+// containers, as in "CT 5 AMP VD X 2 ML" (10 ML). In kits, only the
+// component before the first "+" is measured.
+func parseApresentacao(s string) DetalhesApresentacao {
+	texto := normalizaTexto(s)
+	texto = numeroLetraRegex.ReplaceAllString(texto, "$1 $2")
+	texto = xNumeroRegex.ReplaceAllString(texto, "X $1")
+	texto = sinaisRegex.ReplaceAllString(texto, " $1 ")
+	tokens := strings.Fields(texto)
+
+	var detalhes DetalhesApresentacao
+
+	i := 0
+	temDigito := false
+	for ; i < len(tokens) && tokenConcentracaoRegex.MatchString(tokens[i]); i++ {
+		temDigito = temDigito || strings.ContainsAny(tokens[i], "0123456789")
+	}
+	if temDigito {
+		detalhes.Concentracao = strings.NewReplacer("( ", "(", " )", ")").Replace(strings.Join(tokens[:i], " "))
+	} else {
+		i = 0
+	}
+
+	inicioForma := i
+	for ; i < len(tokens) && !tokensEmbalagem[tokens[i]]; i++ {
+		if _, ok := parseNumeroApresentacao(tokens[i]); ok {
+			break
+		}
+	}
+	detalhes.Forma = strings.Join(tokens[inicioForma:i], " ")
+
+	multiplicador, temMultiplicador := 1.0, false
+	quantidade, unidade, temQuantidade := 0.0, "", false
+	for j := i; j < len(tokens); j++ {
+		// Kits list their other components after "+"; only the first
+		// component is measured.
+		if tokens[j] == "+" {
+			break
+		}
+		if tokens[j] == "X" && j+1 < len(tokens) {
+			partes := quantidadeRegex.FindStringSubmatch(strings.Replace(tokens[j+1], ",", ".", 1))
+			if partes == nil {
+				continue
+			}
+			// Chained factors multiply: "BL AL X 2 X 14" is 28 units.
+			fator, _ := strconv.ParseFloat(partes[1], 64)
+			if !temQuantidade {
+				quantidade = 1
+			}
+			quantidade *= fator
+			if partes[2] != "" {
+				unidade = partes[2]
+			} else if j+2 < len(tokens) {
+				if _, ok := fatoresUnidade[tokens[j+2]]; ok {
+					unidade = tokens[j+2]
+				}
+			}
+			temQuantidade = true
+			j++
+			continue
+		}
+		// Container counts precede the quantity of each container.
+		if contagem, err := strconv.Atoi(tokens[j]); err == nil && !temQuantidade && contagem > 0 {
+			multiplicador *= float64(contagem)
+			temMultiplicador = true
+		}
+	}
+
+	switch {
+	case temQuantidade && quantidade > 0:
+		fator := fatoresUnidade[unidade]
+		total := quantidade * multiplicador * fator.fator
+		detalhes.Quantidade = &total
+		detalhes.Unidade = fator.unidade
+	case temMultiplicador:
+		total := multiplicador
+		detalhes.Quantidade = &total
+		detalhes.Unidade = "UN"
+	}
+	return detalhes
+}
+
+// precoUnitario divides a pack price by the quantity of the pack, giving
+// the price per unit, millilitre or gram. It fails when the quantity is
+// unknown.
+func (d DetalhesApresentacao) precoUnitario(preco float64) (float64, bool) {
+	if d.Quantidade == nil || *d.Quantidade <= 0 {
+		return 0, false
+	}
+	return arredonda(preco / *d.Quantidade, 4), true
+}
+
+// precosUnitarios returns the unit price of every PF and PMVG column with a
+// value, keyed by column, or nil when the quantity of the pack is unknown.
+func precosUnitarios(medicamento Medicamento, detalhes DetalhesApresentacao) map[string]float64 {
+	if _, ok := detalhes.precoUnitario(1); !ok {
+		return nil
+	}
+	precos := make(map[string]float64)
+	for _, header := range cabecalho {
+		if !ehColunaPreco(header) {
+			continue
+		}
+		if preco, ok := medicamento[header].(float64); ok {
+			precos[header], _ = detalhes.precoUnitario(preco)
+		}
+	}
+	return precos
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseApresentacao(t *testing.T) {
+	testCases := []struct {
+		name         string
+		apresentacao string
+		concentracao string
+		forma        string
+		quantidade   float64 // 0 when undetermined
+		unidade      string
+	}{
+		{"ampolas com volume", "5 MG/ML SOL INJ CT 5 AMP VD AMB X 1 ML", "5 MG/ML", "SOL INJ", 5, "ML"},
+		{"frascos sem volume", "1 G PO SOL INJ CT 50 FA", "1 G", "PO SOL INJ", 50, "UN"},
+		{"associação", "(500+125)MG COM REV CT BL AL X 21", "(500 + 125) MG", "COM REV", 21, "UN"},
+		{"blisters", "400MG COM REV CT 2 BL AL X 10", "400 MG", "COM REV", 20, "UN"},
+		{"fatores encadeados", "20 MG CAP DURA CT BL AL X 2 X 14", "20 MG", "CAP DURA", 28, "UN"},
+		{"kit com diluente", "PO SUS OR CT FR VD AMB X 60 ML + SER DOS X 5 ML", "", "PO SUS OR", 60, "ML"},
+		{"kit com blisters", "100 MG CAP DURA CT BL AL PLAS INC X 30 + 1 BL X 10", "100 MG", "CAP DURA", 30, "UN"},
+		{"frasco com volume", "50 MG/ML SOL OR CT FR VD AMB X 100 ML", "50 MG/ML", "SOL OR", 100, "ML"},
+		{"bisnaga em gramas", "10 MG/G CREM DERM CT BG AL X 30G", "10 MG/G", "CREM DERM", 30, "G"},
+		{"bolsas em litros", "0,9% SOL INJ CX 20 BOLSA X 1 L", "0,9 %", "SOL INJ", 20000, "ML"},
+		{"sem quantidade", "COM REV", "", "COM REV", 0, ""},
+		{"sem concentração", "GOTAS", "", "GOTAS", 0, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := parseApresentacao(tc.apresentacao)
+			if result.Concentracao != tc.concentracao || result.Forma != tc.forma || result.Unidade != tc.unidade {
+				t.Errorf("esperado: %q %q %q, obtido: %q %q %q", tc.concentracao, tc.forma, tc.unidade, result.Concentracao, result.Forma, result.Unidade)
+			}
+			var quantidade float64
+			if result.Quantidade != nil {
+				quantidade = *result.Quantidade
+			}
+			if quantidade != tc.quantidade {
+				t.Errorf("esperado: %v, obtido: %v", tc.quantidade, quantidade)
+			}
+		})
+	}
+}
+
+func TestPrecosUnitarios(t *testing.T) {
+	row := make([]string, len(cabecalho))
+	row[9] = "50 MG/ML SOL OR CT FR VD AMB X 100 ML"
+	row[slices.Index(cabecalho, PFSemImpostos)] = "12,50"
+	row[slices.Index(cabecalho, PF18)] = "15,00*"
+	row[slices.Index(cabecalho, PMVGSemImpostos)] = "11,23"
+	medicamento, _ := processaLinha(4, row)
+
+	precos, ok := medicamento[PrecosUnitarios].(map[string]float64)
+	if !ok {
+		t.Fatalf("esperado: mapa de preços, obtido: %v", medicamento[PrecosUnitarios])
+	}
+	expected := map[string]float64{PFSemImpostos: 0.125, PF18: 0.15, PMVGSemImpostos: 0.1123}
+	if !reflect.DeepEqual(precos, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, precos)
+	}
+	if medicamento[QuantidadeIndeterminada] != false {
+		t.Errorf("esperado: %v, obtido: %v", false, medicamento[QuantidadeIndeterminada])
+	}
+
+	row[9] = "SOL OR"
+	medicamento, _ = processaLinha(5, row)
+	if medicamento[PrecosUnitarios] != nil || medicamento[QuantidadeIndeterminada] != true {
+		t.Errorf("esperado: sem preços unitários e quantidade indeterminada, obtido: %v e %v", medicamento[PrecosUnitarios], medicamento[QuantidadeIndeterminada])
+	}
+}
@@ -0,0 +1,285 @@
+// Package busca implements a full-text inverted index over the text fields
+// of a set of documents. Terms are folded to lower case without accents, and
+// queries match terms exactly, by prefix or within an edit distance.
+package busca
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Points of each kind of match between a query term and an index term.
+const (
+	PontosExato      = 3
+	PontosPrefixo    = 2
+	PontosAproximado = 1
+)
+
+// TamanhoMinimoAproximado is the length, in characters, from which query
+// terms are also matched within the edit distance. Shorter terms, such as
+// units ("mg", "ml"), would match too many unrelated terms.
+const TamanhoMinimoAproximado = 4
+
+// Posicao is an occurrence of a term: the document and the field where it
+// appears.
+type Posicao struct {
+	Documento int `json:"d"`
+	Campo     int `json:"c"`
+}
+
+// Indice maps each term to its occurrences. Documents are numbered in the
+// order they are added, starting at zero.
+type Indice struct {
+	Campos     []string             `json:"campos"`
+	Documentos int                  `json:"documentos"`
+	Termos     map[string][]Posicao `json:"termos"`
+
+	// Origem identifies the data the documents were taken from, so that a
+	// saved index can be checked against it. It is not interpreted here.
+	Origem map[string]string `json:"origem,omitempty"`
+
+	ordenados []string // sorted terms, for prefix and fuzzy matching
+}
+
+// Novo creates an empty index over the named fields.
+func Novo(campos ...string) *Indice {
+	return &Indice{Campos: campos, Termos: make(map[string][]Posicao)}
+}
+
+// Normaliza folds a text to lower case without accents.
+func Normaliza(texto string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	resultado, _, _ := transform.String(t, texto)
+	return strings.ToLower(resultado)
+}
+
+// Tokeniza splits a text into normalized terms made of letters and digits.
+func Tokeniza(texto string) []string {
+	return strings.FieldsFunc(Normaliza(texto), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Adiciona indexes a document given the text of each field, in the order of
+// Campos, and returns its number.
+func (i *Indice) Adiciona(textos ...string) int {
+	documento := i.Documentos
+	i.Documentos++
+	for campo, texto := range textos {
+		if campo >= len(i.Campos) {
+			break
+		}
+		for _, termo := range Tokeniza(texto) {
+			posicao := Posicao{Documento: documento, Campo: campo}
+			posicoes := i.Termos[termo]
+			// Positions are added in increasing order, so a repeated term
+			// can only match the last one.
+			if len(posicoes) > 0 && posicoes[len(posicoes)-1] == posicao {
+				continue
+			}
+			i.Termos[termo] = append(posicoes, posicao)
+		}
+	}
+	i.ordenados = nil
+	return documento
+}
+
+// Opcoes configures a search.
+type Opcoes struct {
+	// Prefixo also matches index terms starting with a query term.
+	Prefixo bool
+	// Distancia is the maximum edit distance of approximate matches; zero
+	// disables them.
+	Distancia int
+	// Limite is the maximum number of results; zero returns all of them.
+	Limite int
+}
+
+// Resultado is a document matching every term of the query.
+type Resultado struct {
+	Documento int      `json:"documento"`
+	Pontos    int      `json:"pontos"`
+	Campos    []string `json:"campos"`
+}
+
+// Busca returns the documents containing every term of the query, the best
+// first. Each query term scores the best of its matches in the document:
+// PontosExato, PontosPrefixo or PontosAproximado.
+func (i *Indice) Busca(consulta string, opcoes Opcoes) []Resultado {
+	termosConsulta := Tokeniza(consulta)
+	if len(termosConsulta) == 0 {
+		return []Resultado{}
+	}
+
+	type acumulado struct {
+		pontos int
+		campos []bool
+	}
+	var candidatos map[int]*acumulado
+	for _, termoConsulta := range termosConsulta {
+		melhores := make(map[int]int)
+		campos := make(map[int][]bool)
+		for termo, pontos := range i.correspondencias(termoConsulta, opcoes) {
+			for _, posicao := range i.Termos[termo] {
+				if pontos > melhores[posicao.Documento] {
+					melhores[posicao.Documento] = pontos
+				}
+				if campos[posicao.Documento] == nil {
+					campos[posicao.Documento] = make([]bool, len(i.Campos))
+				}
+				campos[posicao.Documento][posicao.Campo] = true
+			}
+		}
+
+		proximos := make(map[int]*acumulado)
+		for documento, pontos := range melhores {
+			anterior, ok := candidatos[documento]
+			if candidatos != nil && !ok {
+				continue
+			}
+			atual := &acumulado{pontos: pontos, campos: campos[documento]}
+			if ok {
+				atual.pontos += anterior.pontos
+				for campo, encontrado := range anterior.campos {
+					atual.campos[campo] = atual.campos[campo] || encontrado
+				}
+			}
+			proximos[documento] = atual
+		}
+		candidatos = proximos
+	}
+
+	resultados := make([]Resultado, 0, len(candidatos))
+	for documento, a := range candidatos {
+		resultado := Resultado{Documento: documento, Pontos: a.pontos, Campos: []string{}}
+		for campo, encontrado := range a.campos {
+			if encontrado {
+				resultado.Campos = append(resultado.Campos, i.Campos[campo])
+			}
+		}
+		resultados = append(resultados, resultado)
+	}
+	slices.SortFunc(resultados, func(a, b Resultado) int {
+		if a.Pontos != b.Pontos {
+			return b.Pontos - a.Pontos
+		}
+		return a.Documento - b.Documento
+	})
+	if opcoes.Limite > 0 && len(resultados) > opcoes.Limite {
+		resultados = resultados[:opcoes.Limite]
+	}
+	return resultados
+}
+
+// correspondencias returns the index terms matching a query term, with the
+// points of each match.
+func (i *Indice) correspondencias(termoConsulta string, opcoes Opcoes) map[string]int {
+	termos := make(map[string]int)
+	if _, ok := i.Termos[termoConsulta]; ok {
+		termos[termoConsulta] = PontosExato
+	}
+
+	ordenados := i.termosOrdenados()
+	if opcoes.Prefixo {
+		inicio, _ := slices.BinarySearch(ordenados, termoConsulta)
+		for _, termo := range ordenados[inicio:] {
+			if !strings.HasPrefix(termo, termoConsulta) {
+				break
+			}
+			if termo != termoConsulta {
+				termos[termo] = PontosPrefixo
+			}
+		}
+	}
+
+	consulta := []rune(termoConsulta)
+	if opcoes.Distancia > 0 && len(consulta) >= TamanhoMinimoAproximado {
+		for _, termo := range ordenados {
+			if _, ok := termos[termo]; ok {
+				continue
+			}
+			if distanciaEdicao(consulta, []rune(termo), opcoes.Distancia) <= opcoes.Distancia {
+				termos[termo] = PontosAproximado
+			}
+		}
+	}
+	return termos
+}
+
+func (i *Indice) termosOrdenados() []string {
+	if i.ordenados == nil {
+		i.ordenados = make([]string, 0, len(i.Termos))
+		for termo := range i.Termos {
+			i.ordenados = append(i.ordenados, termo)
+		}
+		slices.Sort(i.ordenados)
+	}
+	return i.ordenados
+}
+
+// distanciaEdicao returns the Levenshtein distance between a and b, or
+// maximo+1 as soon as it is known to exceed maximo.
+func distanciaEdicao(a, b []rune, maximo int) int {
+	if diferenca := len(a) - len(b); diferenca > maximo || -diferenca > maximo {
+		return maximo + 1
+	}
+
+	anterior := make([]int, len(b)+1)
+	atual := make([]int, len(b)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		atual[0] = i
+		menor := atual[0]
+		for j := 1; j <= len(b); j++ {
+			custo := 1
+			if a[i-1] == b[j-1] {
+				custo = 0
+			}
+			atual[j] = min(anterior[j]+1, atual[j-1]+1, anterior[j-1]+custo)
+			menor = min(menor, atual[j])
+		}
+		if menor > maximo {
+			return maximo + 1
+		}
+		anterior, atual = atual, anterior
+	}
+	return anterior[len(b)]
+}
+
+// Salva writes the index as gzip-compressed JSON.
+func (i *Indice) Salva(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(i); err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress index: %w", err)
+	}
+	return nil
+}
+
+// Carrega reads an index written by Salva.
+func Carrega(r io.Reader) (*Indice, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	defer gz.Close()
+
+	indice := Novo()
+	if err := json.NewDecoder(gz).Decode(indice); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	return indice, nil
+}
@@ -0,0 +1,164 @@
+package busca
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTokeniza(t *testing.T) {
+	result := Tokeniza("  DIPIRONA MONOIDRATADA;Cloridrato de ORFENADRINA + Cafeína 500MG/ML ")
+	expected := []string{"dipirona", "monoidratada", "cloridrato", "de", "orfenadrina", "cafeina", "500mg", "ml"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %q, obtido: %q", expected, result)
+	}
+}
+
+func indiceTeste() *Indice {
+	indice := Novo("PRODUTO", "SUBSTÂNCIA", "APRESENTAÇÃO", "LABORATÓRIO")
+	indice.Adiciona("DORFLEX", "DIPIRONA MONOIDRATADA;CITRATO DE ORFENADRINA;CAFEÍNA", "300 MG COM CT BL AL X 10", "SANOFI")
+	indice.Adiciona("AMOXIL", "AMOXICILINA", "500 MG CAP GEL DURA X 21", "GSK")
+	indice.Adiciona("AMOXICILINA", "AMOXICILINA", "500 MG CAP GEL DURA X 21", "EMS")
+	indice.Adiciona("NOVALGINA", "DIPIRONA", "500 MG/ML SOL GOT X 20 ML", "SANOFI")
+	return indice
+}
+
+func TestAdicionaTermoRepetido(t *testing.T) {
+	indice := Novo("PRODUTO", "APRESENTAÇÃO")
+	indice.Adiciona("SORO", "SOL INJ CX 20 BOLSA X 20 ML")
+	indice.Adiciona("SORO FISIOLÓGICO SORO", "SOL INJ")
+
+	expected := []Posicao{{Documento: 0, Campo: 0}, {Documento: 1, Campo: 0}}
+	if result := indice.Termos["soro"]; !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, result)
+	}
+	expected = []Posicao{{Documento: 0, Campo: 1}}
+	if result := indice.Termos["20"]; !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, result)
+	}
+}
+
+func TestBusca(t *testing.T) {
+	indice := indiceTeste()
+
+	testCases := []struct {
+		name     string
+		consulta string
+		opcoes   Opcoes
+		expected []Resultado
+	}{
+		{
+			name:     "exato sem acento",
+			consulta: "cafeina",
+			expected: []Resultado{{Documento: 0, Pontos: PontosExato, Campos: []string{"SUBSTÂNCIA"}}},
+		},
+		{
+			name:     "todos os termos",
+			consulta: "Dipirona Sanofi",
+			expected: []Resultado{
+				{Documento: 0, Pontos: 2 * PontosExato, Campos: []string{"SUBSTÂNCIA", "LABORATÓRIO"}},
+				{Documento: 3, Pontos: 2 * PontosExato, Campos: []string{"SUBSTÂNCIA", "LABORATÓRIO"}},
+			},
+		},
+		{
+			name:     "prefixo",
+			consulta: "amoxi",
+			opcoes:   Opcoes{Prefixo: true},
+			expected: []Resultado{
+				{Documento: 1, Pontos: PontosPrefixo, Campos: []string{"PRODUTO", "SUBSTÂNCIA"}},
+				{Documento: 2, Pontos: PontosPrefixo, Campos: []string{"PRODUTO", "SUBSTÂNCIA"}},
+			},
+		},
+		{
+			name:     "prefixo desativado",
+			consulta: "amoxi",
+			expected: []Resultado{},
+		},
+		{
+			name:     "aproximado",
+			consulta: "amoxicilna ems",
+			opcoes:   Opcoes{Distancia: 1},
+			expected: []Resultado{
+				{Documento: 2, Pontos: PontosAproximado + PontosExato, Campos: []string{"PRODUTO", "SUBSTÂNCIA", "LABORATÓRIO"}},
+			},
+		},
+		{
+			name:     "termo curto não é aproximado",
+			consulta: "gsx",
+			opcoes:   Opcoes{Distancia: 1},
+			expected: []Resultado{},
+		},
+		{
+			name:     "limite",
+			consulta: "500",
+			opcoes:   Opcoes{Limite: 2},
+			expected: []Resultado{
+				{Documento: 1, Pontos: PontosExato, Campos: []string{"APRESENTAÇÃO"}},
+				{Documento: 2, Pontos: PontosExato, Campos: []string{"APRESENTAÇÃO"}},
+			},
+		},
+		{
+			name:     "consulta vazia",
+			consulta: " ; ",
+			expected: []Resultado{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := indice.Busca(tc.consulta, tc.opcoes)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("esperado: %+v, obtido: %+v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDistanciaEdicao(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     string
+		maximo   int
+		expected int
+	}{
+		{"iguais", "dipirona", "dipirona", 2, 0},
+		{"substituição", "dipirona", "dipyrona", 2, 1},
+		{"remoção", "dipirona", "dipiron", 2, 1},
+		{"transposição", "orfenadrina", "orfenadirna", 2, 2},
+		{"acima do máximo 1", "amoxicilina", "ampicilina", 1, 2},
+		{"acima do máximo 2", "cafeina", "ibuprofeno", 2, 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := distanciaEdicao([]rune(tc.a), []rune(tc.b), tc.maximo); result != tc.expected {
+				t.Errorf("esperado: %d, obtido: %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSalvaCarrega(t *testing.T) {
+	indice := indiceTeste()
+
+	var buf bytes.Buffer
+	if err := indice.Salva(&buf); err != nil {
+		t.Fatalf("Salva failed: %v", err)
+	}
+	carregado, err := Carrega(&buf)
+	if err != nil {
+		t.Fatalf("Carrega failed: %v", err)
+	}
+
+	if carregado.Documentos != indice.Documentos || !reflect.DeepEqual(carregado.Campos, indice.Campos) {
+		t.Errorf("esperado: %d documentos %v, obtido: %d documentos %v", indice.Documentos, indice.Campos, carregado.Documentos, carregado.Campos)
+	}
+	consulta, opcoes := "dipir sanofi", Opcoes{Prefixo: true}
+	if result, expected := carregado.Busca(consulta, opcoes), indice.Busca(consulta, opcoes); !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %+v, obtido: %+v", expected, result)
+	}
+
+	if _, err := Carrega(bytes.NewReader([]byte("{}"))); err == nil {
+		t.Error("esperado erro para arquivo não compactado")
+	}
+}
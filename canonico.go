@@ -0,0 +1,58 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ordenaOutput puts the output in canonical order, so processing the same
+// spreadsheet always produces byte-identical files. Map keys are already
+// sorted by encoding/json; lists are sorted here and medicines are ordered
+// by GGREM code, with medicines without a code at the end. Presentation and
+// equivalence group IDs are kept, so the references from medicines remain
+// valid.
+func ordenaOutput(output *Output) {
+	sort.SliceStable(output.Medicamentos, func(i, j int) bool {
+		a, b := output.Medicamentos[i], output.Medicamentos[j]
+		for _, header := range []string{CodigoGGREM, EAN1, Registro} {
+			va, okA := a[header].(string)
+			vb, okB := b[header].(string)
+			if okA != okB {
+				return okA
+			}
+			if va != vb {
+				return va < vb
+			}
+		}
+		return false
+	})
+
+	sort.SliceStable(output.Apresentacoes, func(i, j int) bool {
+		return output.Apresentacoes[i].Descricao < output.Apresentacoes[j].Descricao
+	})
+	for _, apresentacao := range output.Apresentacoes {
+		slices.Sort(apresentacao.Variantes)
+		slices.Sort(apresentacao.CodigosGGREM)
+	}
+
+	for _, ggrems := range output.Substancias {
+		slices.Sort(ggrems)
+	}
+
+	sort.SliceStable(output.GruposEquivalencia, func(i, j int) bool {
+		a, b := output.GruposEquivalencia[i], output.GruposEquivalencia[j]
+		if chaveA, chaveB := strings.Join(a.Substancias, ";"), strings.Join(b.Substancias, ";"); chaveA != chaveB {
+			return chaveA < chaveB
+		}
+		if a.Concentracao != b.Concentracao {
+			return a.Concentracao < b.Concentracao
+		}
+		return a.Forma < b.Forma
+	})
+	for _, grupo := range output.GruposEquivalencia {
+		sort.SliceStable(grupo.Membros, func(i, j int) bool {
+			return grupo.Membros[i].CodigoGGREM < grupo.Membros[j].CodigoGGREM
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrdenaOutput(t *testing.T) {
+	output := Output{
+		Medicamentos: []Medicamento{
+			{CodigoGGREM: nil, Produto: "SEM CODIGO"},
+			{CodigoGGREM: "538912020000003", Produto: "B"},
+			{CodigoGGREM: "500101101111417", EAN1: "7896004703398", Produto: "A2"},
+			{CodigoGGREM: "500101101111417", EAN1: "7896004703381", Produto: "A1"},
+		},
+		Apresentacoes: []ApresentacaoCatalogo{
+			{ID: 1, Descricao: "GOTAS", Variantes: []string{"GOTAS"}, CodigosGGREM: []string{"538912020000003"}},
+			{ID: 2, Descricao: "COM REV", Variantes: []string{"COM RÉV", "COM REV"}, CodigosGGREM: []string{"500101101111417", "500101101111416"}},
+			{ID: 3, Descricao: "CAP DURA", Variantes: []string{"CAP DURA"}, CodigosGGREM: []string{}},
+		},
+		Substancias: map[string][]string{
+			"DIPIRONA": {"538912020000003", "500101101111417"},
+		},
+	}
+
+	ordenaOutput(&output)
+
+	var produtos []string
+	for _, medicamento := range output.Medicamentos {
+		produtos = append(produtos, medicamento[Produto].(string))
+	}
+	if expected := []string{"A1", "A2", "B", "SEM CODIGO"}; !reflect.DeepEqual(produtos, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, produtos)
+	}
+	expectedApresentacoes := []ApresentacaoCatalogo{
+		{ID: 3, Descricao: "CAP DURA", Variantes: []string{"CAP DURA"}, CodigosGGREM: []string{}},
+		{ID: 2, Descricao: "COM REV", Variantes: []string{"COM REV", "COM RÉV"}, CodigosGGREM: []string{"500101101111416", "500101101111417"}},
+		{ID: 1, Descricao: "GOTAS", Variantes: []string{"GOTAS"}, CodigosGGREM: []string{"538912020000003"}},
+	}
+	if !reflect.DeepEqual(output.Apresentacoes, expectedApresentacoes) {
+		t.Errorf("esperado: %+v, obtido: %+v", expectedApresentacoes, output.Apresentacoes)
+	}
+	if expected := []string{"500101101111417", "538912020000003"}; !reflect.DeepEqual(output.Substancias["DIPIRONA"], expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, output.Substancias["DIPIRONA"])
+	}
+}
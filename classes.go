@@ -0,0 +1,40 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var classeTerapeuticaRegex = regexp.MustCompile(`^([A-Z][0-9A-Z]*)\s*-\s*(.*)$`)
+var nivelClasseRegex = regexp.MustCompile(`[A-Z]|[0-9]+`)
+
+type ClassificacaoTerapeutica struct {
+	Codigo    string   `json:"codigo" xml:"Codigo"`
+	Descricao string   `json:"descricao" xml:"Descricao"`
+	Niveis    []string `json:"niveis" xml:"Niveis>Nivel"`
+}
+
+// parseClasseTerapeutica splits a CLASSE TERAPÊUTICA value such as
+// "M1A - ANTI-REUMÁTICOS NÃO ESTEROIDES" into its EphMRA code, description
+// and hierarchy levels (M, M1, M1A). It returns false when the value does
+// not start with a code.
+func parseClasseTerapeutica(s string) (ClassificacaoTerapeutica, bool) {
+	matches := classeTerapeuticaRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return ClassificacaoTerapeutica{}, false
+	}
+
+	codigo := matches[1]
+	var niveis []string
+	nivel := ""
+	for _, parte := range nivelClasseRegex.FindAllString(codigo, -1) {
+		nivel += parte
+		niveis = append(niveis, nivel)
+	}
+
+	return ClassificacaoTerapeutica{
+		Codigo:    codigo,
+		Descricao: strings.TrimSpace(matches[2]),
+		Niveis:    niveis,
+	}, true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClasseTerapeutica(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected ClassificacaoTerapeutica
+		ok       bool
+	}{
+		{
+			"três níveis",
+			"M1A - ANTI-REUMÁTICOS NÃO ESTEROIDES",
+			ClassificacaoTerapeutica{"M1A", "ANTI-REUMÁTICOS NÃO ESTEROIDES", []string{"M", "M1", "M1A"}},
+			true,
+		},
+		{
+			"quatro níveis",
+			"A2B1 - ANTAGONISTAS H2",
+			ClassificacaoTerapeutica{"A2B1", "ANTAGONISTAS H2", []string{"A", "A2", "A2B", "A2B1"}},
+			true,
+		},
+		{
+			"sem espaços no separador",
+			"N2B-ANALGÉSICOS NÃO NARCÓTICOS",
+			ClassificacaoTerapeutica{"N2B", "ANALGÉSICOS NÃO NARCÓTICOS", []string{"N", "N2", "N2B"}},
+			true,
+		},
+		{
+			"dígitos múltiplos",
+			"J01C - PENICILINAS",
+			ClassificacaoTerapeutica{"J01C", "PENICILINAS", []string{"J", "J01", "J01C"}},
+			true,
+		},
+		{"sem código", "ANALGÉSICOS", ClassificacaoTerapeutica{}, false},
+		{"string vazia", "", ClassificacaoTerapeutica{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := parseClasseTerapeutica(tc.input)
+			if ok != tc.ok {
+				t.Fatalf("esperado ok: %v, obtido: %v", tc.ok, ok)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("esperado: %+v, obtido: %+v", tc.expected, result)
+			}
+		})
+	}
+}
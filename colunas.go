@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// colunasPorNome maps the name of each column constant to the key it has in
+// a medicine, so columns can be selected without typing accents or spaces.
+var colunasPorNome = map[string]string{
+	"PrincipioAtivo":                  PrincipioAtivo,
+	"CNPJ":                            CNPJ,
+	"Laboratorio":                     Laboratorio,
+	"CodigoGGREM":                     CodigoGGREM,
+	"Registro":                        Registro,
+	"EAN1":                            EAN1,
+	"EAN2":                            EAN2,
+	"EAN3":                            EAN3,
+	"Produto":                         Produto,
+	"Apresentacao":                    Apresentacao,
+	"ClasseTerapeutica":               ClasseTerapeutica,
+	"Tipo":                            Tipo,
+	"RegimePreco":                     RegimePreco,
+	"PFSemImpostos":                   PFSemImpostos,
+	"PF0":                             PF0,
+	"PF12":                            PF12,
+	"PF12ALC":                         PF12ALC,
+	"PF17":                            PF17,
+	"PF17ALC":                         PF17ALC,
+	"PF175":                           PF175,
+	"PF175ALC":                        PF175ALC,
+	"PF18":                            PF18,
+	"PF18ALC":                         PF18ALC,
+	"PF19":                            PF19,
+	"PF19ALC":                         PF19ALC,
+	"PF195":                           PF195,
+	"PF195ALC":                        PF195ALC,
+	"PF20":                            PF20,
+	"PF20ALC":                         PF20ALC,
+	"PF205":                           PF205,
+	"PF205ALC":                        PF205ALC,
+	"PF21":                            PF21,
+	"PF21ALC":                         PF21ALC,
+	"PF22":                            PF22,
+	"PF22ALC":                         PF22ALC,
+	"PF225":                           PF225,
+	"PF225ALC":                        PF225ALC,
+	"PF23":                            PF23,
+	"PF23ALC":                         PF23ALC,
+	"PMVGSemImpostos":                 PMVGSemImpostos,
+	"PMVG0":                           PMVG0,
+	"PMVG12":                          PMVG12,
+	"PMVG12ALC":                       PMVG12ALC,
+	"PMVG17":                          PMVG17,
+	"PMVG17ALC":                       PMVG17ALC,
+	"PMVG175":                         PMVG175,
+	"PMVG175ALC":                      PMVG175ALC,
+	"PMVG18":                          PMVG18,
+	"PMVG18ALC":                       PMVG18ALC,
+	"PMVG19":                          PMVG19,
+	"PMVG19ALC":                       PMVG19ALC,
+	"PMVG195":                         PMVG195,
+	"PMVG195ALC":                      PMVG195ALC,
+	"PMVG20":                          PMVG20,
+	"PMVG20ALC":                       PMVG20ALC,
+	"PMVG205":                         PMVG205,
+	"PMVG205ALC":                      PMVG205ALC,
+	"PMVG21":                          PMVG21,
+	"PMVG21ALC":                       PMVG21ALC,
+	"PMVG22":                          PMVG22,
+	"PMVG22ALC":                       PMVG22ALC,
+	"PMVG225":                         PMVG225,
+	"PMVG225ALC":                      PMVG225ALC,
+	"PMVG23":                          PMVG23,
+	"PMVG23ALC":                       PMVG23ALC,
+	"RestricaoHospitalar":             RestricaoHospitalar,
+	"CAP":                             CAP,
+	"Confaz87":                        Confaz87,
+	"ICMS0":                           ICMS0,
+	"AnaliseRecursal":                 AnaliseRecursal,
+	"ListaConcessaoCreditoTributario": ListaConcessaoCreditoTributario,
+	"Comercializacao2024":             Comercializacao2024,
+	"Tarja":                           Tarja,
+	"Substancias":                     Substancias,
+	"Classificacao":                   Classificacao,
+	"TipoProdutoCodigo":               TipoProdutoCodigo,
+	"RegimePrecoCodigo":               RegimePrecoCodigo,
+	"TarjaCodigo":                     TarjaCodigo,
+	"ListaPISCOFINSCodigo":            ListaPISCOFINSCodigo,
+	"PrecosMarcados":                  PrecosMarcados,
+	"ApresentacaoID":                  ApresentacaoID,
+	"ApresentacaoDetalhes":            ApresentacaoDetalhes,
+	"GrupoEquivalenciaID":             GrupoEquivalenciaID,
+	"PrecosUnitarios":                 PrecosUnitarios,
+	"QuantidadeIndeterminada":         QuantidadeIndeterminada,
+	"Linha":                           Linha,
+}
+
+// Projecao selects the fields of each medicine written to the output.
+type Projecao struct {
+	// Colunas lists the fields kept; nil keeps all of them.
+	Colunas     []string
+	Excluidas   []string
+	OmitirNulos bool
+}
+
+func (p Projecao) ativa() bool {
+	return p.Colunas != nil || len(p.Excluidas) > 0 || p.OmitirNulos
+}
+
+// colunas returns the selected fields, in spreadsheet order followed by the
+// derived fields.
+func (p Projecao) colunas() []string {
+	incluidas := make(map[string]bool)
+	for _, coluna := range p.Colunas {
+		incluidas[coluna] = true
+	}
+	excluidas := make(map[string]bool)
+	for _, coluna := range p.Excluidas {
+		excluidas[coluna] = true
+	}
+
+	var colunas []string
+	for _, coluna := range slices.Concat(cabecalho, camposDerivados) {
+		if (p.Colunas == nil || incluidas[coluna]) && !excluidas[coluna] {
+			colunas = append(colunas, coluna)
+		}
+	}
+	return colunas
+}
+
+// aplica projects the medicines of the output and records the projection in
+// its metadata. Catalogs and indexes are left untouched.
+func (p Projecao) aplica(output *Output) {
+	if !p.ativa() {
+		return
+	}
+
+	colunas := p.colunas()
+	for i, medicamento := range output.Medicamentos {
+		projetado := make(Medicamento, len(colunas))
+		for _, coluna := range colunas {
+			valor, ok := medicamento[coluna]
+			if !ok || (valor == nil && p.OmitirNulos) {
+				continue
+			}
+			projetado[coluna] = valor
+		}
+		output.Medicamentos[i] = projetado
+	}
+
+	output.Metadados.Colunas = colunas
+	output.Metadados.OmiteNulos = p.OmitirNulos
+}
+
+// resolveColuna finds the medicine field named by a column constant (as in
+// "PF18ALC") or by its key (as in "PF 18% ALC" or "tarja"). Exact matches
+// come first, since derived keys such as "tarja" differ from a constant
+// name only by case; then case and accents are ignored.
+func resolveColuna(nome string) (string, bool) {
+	nome = strings.TrimSpace(nome)
+	if coluna, ok := colunasPorNome[nome]; ok {
+		return coluna, true
+	}
+	for _, coluna := range slices.Concat(cabecalho, camposDerivados) {
+		if coluna == nome {
+			return coluna, true
+		}
+	}
+	for constante, coluna := range colunasPorNome {
+		if strings.EqualFold(constante, nome) {
+			return coluna, true
+		}
+	}
+	normalizado := normalizaTexto(nome)
+	for _, coluna := range slices.Concat(cabecalho, camposDerivados) {
+		if normalizaTexto(coluna) == normalizado {
+			return coluna, true
+		}
+	}
+	return "", false
+}
+
+// parseColunas parses a comma-separated list of columns. Header texts may
+// themselves contain commas ("PF 17,5%"), so a part that names no column is
+// joined with the next ones until it does.
+func parseColunas(lista string) ([]string, error) {
+	colunas := []string{}
+	partes := strings.Split(lista, ",")
+	for i := 0; i < len(partes); i++ {
+		if strings.TrimSpace(partes[i]) == "" {
+			continue
+		}
+		nome := partes[i]
+		coluna, ok := resolveColuna(nome)
+		for j := i + 1; !ok && j < len(partes); j++ {
+			if coluna, ok = resolveColuna(strings.Join(partes[i:j+1], ",")); ok {
+				i = j
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("coluna desconhecida: '%s'", strings.TrimSpace(nome))
+		}
+		colunas = append(colunas, coluna)
+	}
+	return colunas, nil
+}
+
+// novaProjecao builds the projection from the values of the --columns,
+// --exclude-columns and --omit-null flags.
+func novaProjecao(colunas, excluidas string, omitirNulos bool) (Projecao, error) {
+	projecao := Projecao{OmitirNulos: omitirNulos}
+	var err error
+	if strings.TrimSpace(colunas) != "" {
+		if projecao.Colunas, err = parseColunas(colunas); err != nil {
+			return Projecao{}, err
+		}
+	}
+	if projecao.Excluidas, err = parseColunas(excluidas); err != nil {
+		return Projecao{}, err
+	}
+	return projecao, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestColunasPorNome(t *testing.T) {
+	if len(colunasPorNome) != len(cabecalho)+len(camposDerivados) {
+		t.Errorf("esperado: %d colunas, obtido: %d", len(cabecalho)+len(camposDerivados), len(colunasPorNome))
+	}
+	for _, coluna := range slices.Concat(cabecalho, camposDerivados) {
+		if result, ok := resolveColuna(coluna); !ok || result != coluna {
+			t.Errorf("esperado: %q, obtido: %q", coluna, result)
+		}
+	}
+}
+
+func TestParseColunas(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lista    string
+		expected []string
+		erro     bool
+	}{
+		{"lista vazia", "", []string{}, false},
+		{"chaves JSON", "PrincipioAtivo,CodigoGGREM", []string{PrincipioAtivo, CodigoGGREM}, false},
+		{"espaços e maiúsculas", " pf18alc , Substancias", []string{PF18ALC, Substancias}, false},
+		{"cabeçalhos com vírgula", "substancia,PF 17,5%,PF 18%", []string{PrincipioAtivo, PF175, PF18}, false},
+		{"aliases", "Apresentacao,PF 17,5% ALC,classificacaoTerapeutica", []string{Apresentacao, PF175ALC, Classificacao}, false},
+		{"coluna desconhecida", "Produto,Preco", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseColunas(tc.lista)
+			if tc.erro {
+				if err == nil {
+					t.Errorf("esperado erro para %q", tc.lista)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseColunas failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("esperado: %q, obtido: %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestProjecao(t *testing.T) {
+	novoOutput := func() Output {
+		return Output{Medicamentos: []Medicamento{
+			{PrincipioAtivo: "IBUPROFENO", CodigoGGREM: "1", PF18: 12.5, EAN2: nil, Linha: 4},
+			{PrincipioAtivo: "PARACETAMOL", CodigoGGREM: nil, PF18: nil, EAN2: nil, Linha: 5},
+		}}
+	}
+
+	testCases := []struct {
+		name     string
+		projecao Projecao
+		expected []Medicamento
+		colunas  []string
+	}{
+		{
+			name:     "colunas",
+			projecao: Projecao{Colunas: []string{PF18, PrincipioAtivo}},
+			expected: []Medicamento{
+				{PrincipioAtivo: "IBUPROFENO", PF18: 12.5},
+				{PrincipioAtivo: "PARACETAMOL", PF18: nil},
+			},
+			colunas: []string{PrincipioAtivo, PF18},
+		},
+		{
+			name:     "colunas sem nulos",
+			projecao: Projecao{Colunas: []string{PrincipioAtivo, CodigoGGREM, PF18, EAN2}, OmitirNulos: true},
+			expected: []Medicamento{
+				{PrincipioAtivo: "IBUPROFENO", CodigoGGREM: "1", PF18: 12.5},
+				{PrincipioAtivo: "PARACETAMOL"},
+			},
+			colunas: []string{PrincipioAtivo, CodigoGGREM, EAN2, PF18},
+		},
+		{
+			name:     "exclusao",
+			projecao: Projecao{Excluidas: []string{PF18, EAN2}},
+			expected: []Medicamento{
+				{PrincipioAtivo: "IBUPROFENO", CodigoGGREM: "1", Linha: 4},
+				{PrincipioAtivo: "PARACETAMOL", CodigoGGREM: nil, Linha: 5},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := novoOutput()
+			tc.projecao.aplica(&output)
+			if !reflect.DeepEqual(output.Medicamentos, tc.expected) {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, output.Medicamentos)
+			}
+			if tc.colunas != nil && !reflect.DeepEqual(output.Metadados.Colunas, tc.colunas) {
+				t.Errorf("esperado: %v, obtido: %v", tc.colunas, output.Metadados.Colunas)
+			}
+			if output.Metadados.OmiteNulos != tc.projecao.OmitirNulos {
+				t.Errorf("esperado: %v, obtido: %v", tc.projecao.OmitirNulos, output.Metadados.OmiteNulos)
+			}
+		})
+	}
+
+	output := novoOutput()
+	Projecao{}.aplica(&output)
+	if expected := novoOutput(); !reflect.DeepEqual(output, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, output)
+	}
+}
+
+func TestOutputProjetadoValidaSchema(t *testing.T) {
+	schema := schemaDecodificado(t)
+
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(linhasSinteticas(5), data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+	projecao, err := novaProjecao("CodigoGGREM,PF 17,5%,EAN 2,substancias", "", true)
+	if err != nil {
+		t.Fatalf("novaProjecao failed: %v", err)
+	}
+	projecao.aplica(&output)
+
+	jsonData, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("failed to encode json: %v", err)
+	}
+	var documento any
+	if err := json.Unmarshal(jsonData, &documento); err != nil {
+		t.Fatalf("failed to decode json: %v", err)
+	}
+	for _, erro := range validaSchema(schema, schema, documento, "") {
+		t.Error(erro)
+	}
+
+	if _, ok := output.Medicamentos[0][EAN2]; ok {
+		t.Errorf("esperado: EAN 2 nulo omitido, obtido: %v", output.Medicamentos[0])
+	}
+	if len(output.Medicamentos[0]) != 3 {
+		t.Errorf("esperado: 3 campos, obtido: %v", output.Medicamentos[0])
+	}
+}
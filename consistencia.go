@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmed-parser/impostos"
+)
+
+var colunaAliquotaRegex = regexp.MustCompile(`^(PF|PMVG) ([0-9]+(?:,[0-9]+)?)%( ALC)?$`)
+
+type Inconsistencia struct {
+	Linha       int     `json:"linha"`
+	CodigoGGREM string  `json:"codigoGgrem,omitempty"`
+	Coluna      string  `json:"coluna"`
+	Esperado    float64 `json:"esperado"`
+	Encontrado  float64 `json:"encontrado"`
+	Motivo      string  `json:"motivo"`
+}
+
+// aliquotaColuna parses a price column header such as "PF 17,5% ALC" into
+// its price type (PF or PMVG), ICMS rate in percent and whether it is a
+// free trade area (ALC) column. It returns false for other columns,
+// including "PF Sem Impostos" and "PMVG Sem Impostos".
+func aliquotaColuna(header string) (tipo string, aliquota float64, alc bool, ok bool) {
+	matches := colunaAliquotaRegex.FindStringSubmatch(header)
+	if matches == nil {
+		return "", 0, false, false
+	}
+	aliquota, err := strconv.ParseFloat(strings.Replace(matches[2], ",", ".", 1), 64)
+	if err != nil {
+		return "", 0, false, false
+	}
+	return matches[1], aliquota, matches[3] != "", true
+}
+
+// linhaMedicamento returns the spreadsheet row of a medicine, which is an
+// int when parsed from the spreadsheet and a float64 when loaded from JSON.
+func linhaMedicamento(medicamento Medicamento) int {
+	switch linha := medicamento[Linha].(type) {
+	case int:
+		return linha
+	case float64:
+		return int(linha)
+	}
+	return 0
+}
+
+func arredondaCentavos(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// verificaConsistencia checks that, for the medicines subject to the CAP
+// (CAP column "Sim"), every PMVG equals the PF of the same rate with the CAP
+// discount applied (coeficienteCAP is the coefficient, e.g. 0.2192), and
+// that PF and PMVG never decrease as the ICMS rate increases. Differences up
+// to tolerancia are accepted to absorb rounding.
+func verificaConsistencia(medicamentos []Medicamento, coeficienteCAP, tolerancia float64) []Inconsistencia {
+	inconsistencias := []Inconsistencia{}
+
+	for _, medicamento := range medicamentos {
+		linha := linhaMedicamento(medicamento)
+		ggrem, _ := medicamento[CodigoGGREM].(string)
+
+		sujeitoCAP, _ := medicamento[CAP].(bool)
+		for _, header := range cabecalho {
+			if !sujeitoCAP || !strings.HasPrefix(header, "PF ") {
+				continue
+			}
+			pf, okPF := medicamento[header].(float64)
+			colunaPMVG := "PMVG" + strings.TrimPrefix(header, "PF")
+			pmvg, okPMVG := medicamento[colunaPMVG].(float64)
+			if !okPF || !okPMVG {
+				continue
+			}
+			esperado := arredondaCentavos(pf * (1 - coeficienteCAP))
+			if math.Abs(esperado-pmvg) > tolerancia {
+				inconsistencias = append(inconsistencias, Inconsistencia{
+					Linha:       linha,
+					CodigoGGREM: ggrem,
+					Coluna:      colunaPMVG,
+					Esperado:    esperado,
+					Encontrado:  pmvg,
+					Motivo:      fmt.Sprintf("PMVG difere do %s com o desconto do CAP", header),
+				})
+			}
+		}
+
+		for _, tipo := range []string{"PF", "PMVG"} {
+			for _, alc := range []bool{false, true} {
+				// cabecalho lists the rates in increasing order, starting
+				// from the price without taxes.
+				anterior := tipo + " Sem Impostos"
+				for _, header := range cabecalho {
+					tipoColuna, _, alcColuna, ok := aliquotaColuna(header)
+					if !ok || tipoColuna != tipo || alcColuna != alc {
+						continue
+					}
+					valorAnterior, okAnterior := medicamento[anterior].(float64)
+					valor, ok := medicamento[header].(float64)
+					if !ok {
+						continue
+					}
+					if okAnterior && valor < valorAnterior-tolerancia {
+						inconsistencias = append(inconsistencias, Inconsistencia{
+							Linha:       linha,
+							CodigoGGREM: ggrem,
+							Coluna:      header,
+							Esperado:    valorAnterior,
+							Encontrado:  valor,
+							Motivo:      fmt.Sprintf("preço menor que o da coluna %s, de alíquota menor", anterior),
+						})
+					}
+					anterior = header
+				}
+			}
+		}
+	}
+
+	return inconsistencias
+}
+
+// listaMedicamento returns the PIS/COFINS list of a medicine, which is a
+// ListaPISCOFINS when parsed from the spreadsheet and a string when loaded
+// from JSON.
+func listaMedicamento(medicamento Medicamento) (impostos.Lista, bool) {
+	switch lista := medicamento[ListaPISCOFINSCodigo].(type) {
+	case ListaPISCOFINS:
+		return impostos.Lista(lista), true
+	case string:
+		return impostos.Lista(lista), true
+	}
+	return "", false
+}
+
+// verificaImpostos checks that every PF and PMVG equals the respective price
+// without taxes grossed up by the ICMS rate of the column and the
+// PIS/COFINS rate of the medicine's list.
+func verificaImpostos(medicamentos []Medicamento, aliquotas impostos.Aliquotas, tolerancia float64) []Inconsistencia {
+	inconsistencias := []Inconsistencia{}
+
+	for _, medicamento := range medicamentos {
+		lista, ok := listaMedicamento(medicamento)
+		if !ok {
+			continue
+		}
+		linha := linhaMedicamento(medicamento)
+		ggrem, _ := medicamento[CodigoGGREM].(string)
+
+		for _, header := range cabecalho {
+			tipo, aliquota, alc, ok := aliquotaColuna(header)
+			if !ok {
+				continue
+			}
+			colunaBase := tipo + " Sem Impostos"
+			base, okBase := medicamento[colunaBase].(float64)
+			valor, okValor := medicamento[header].(float64)
+			if !okBase || !okValor {
+				continue
+			}
+			esperado, ok := aliquotas.Preco(base, aliquota/100, lista, alc)
+			if !ok {
+				continue
+			}
+			if math.Abs(esperado-valor) > tolerancia {
+				pisCofins, _ := aliquotas.PISCOFINS(lista, alc)
+				inconsistencias = append(inconsistencias, Inconsistencia{
+					Linha:       linha,
+					CodigoGGREM: ggrem,
+					Coluna:      header,
+					Esperado:    esperado,
+					Encontrado:  valor,
+					Motivo:      fmt.Sprintf("preço difere do %s com ICMS de %s%% e PIS/COFINS de %s%%", colunaBase, formataPorcentagem(aliquota), formataPorcentagem(pisCofins*100)),
+				})
+			}
+		}
+	}
+
+	return inconsistencias
+}
+
+func formataPorcentagem(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1)
+}
+
+func runVerificar(args []string) {
+	flags := flag.NewFlagSet("verificar", flag.ExitOnError)
+	coeficienteCAP := flags.Float64("cap", -1, "Coeficiente de Adequação de Preço (por exemplo, 0.2192)")
+	tolerancia := flags.Float64("tolerancia", 0.01, "Diferença máxima aceita, em reais")
+	verificarImpostos := flags.Bool("impostos", false, "Verificar também os preços calculados a partir dos preços sem impostos")
+	positiva := flags.Float64("pis-cofins-positiva", impostos.AliquotasPadrao[impostos.Positiva], "Alíquota de PIS/COFINS da lista positiva, como fração")
+	negativa := flags.Float64("pis-cofins-negativa", impostos.AliquotasPadrao[impostos.Negativa], "Alíquota de PIS/COFINS da lista negativa, como fração")
+	neutra := flags.Float64("pis-cofins-neutra", impostos.AliquotasPadrao[impostos.Neutra], "Alíquota de PIS/COFINS da lista neutra, como fração")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *coeficienteCAP < 0 || *coeficienteCAP >= 1 {
+		log.Fatal("Uso: cmed-parser verificar --cap <coeficiente> [flags] <arquivo.xlsx|json|zip>")
+	}
+
+	hoje := time.Now()
+	output, err := carregaOutput(flags.Arg(0), hoje, hoje)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inconsistencias := verificaConsistencia(output.Medicamentos, *coeficienteCAP, *tolerancia)
+	if *verificarImpostos {
+		aliquotas := impostos.Aliquotas{
+			impostos.Positiva: *positiva,
+			impostos.Negativa: *negativa,
+			impostos.Neutra:   *neutra,
+		}
+		inconsistencias = append(inconsistencias, verificaImpostos(output.Medicamentos, aliquotas, *tolerancia)...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(inconsistencias); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d inconsistências encontradas", len(inconsistencias))
+}
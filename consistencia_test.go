@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"cmed-parser/impostos"
+)
+
+func TestAliquotaColuna(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		tipo     string
+		aliquota float64
+		alc      bool
+		ok       bool
+	}{
+		{"PF inteiro", "PF 18%", "PF", 18, false, true},
+		{"PF decimal ALC", "PF 17,5% ALC", "PF", 17.5, true, true},
+		{"PMVG zero", "PMVG 0%", "PMVG", 0, false, true},
+		{"sem impostos", "PF Sem Impostos", "", 0, false, false},
+		{"outra coluna", "CAP", "", 0, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tipo, aliquota, alc, ok := aliquotaColuna(tc.header)
+			if tipo != tc.tipo || aliquota != tc.aliquota || alc != tc.alc || ok != tc.ok {
+				t.Errorf("esperado: %s %v %v %v, obtido: %s %v %v %v", tc.tipo, tc.aliquota, tc.alc, tc.ok, tipo, aliquota, alc, ok)
+			}
+		})
+	}
+}
+
+func TestVerificaConsistencia(t *testing.T) {
+	medicamentos := []Medicamento{
+		{
+			Linha:           4,
+			CodigoGGREM:     "1",
+			CAP:             true,
+			PFSemImpostos:   80.0,
+			PF0:             90.0,
+			PF12:            100.0,
+			PF12ALC:         95.0,
+			PMVGSemImpostos: 60.0,
+			PMVG0:           67.5,
+			PMVG12:          75.0,
+			PMVG12ALC:       71.25,
+		},
+		{
+			Linha:         5.0,
+			CodigoGGREM:   "2",
+			CAP:           true,
+			PFSemImpostos: 80.0,
+			PF0:           90.0,
+			PF12:          85.0,
+			PF17:          nil,
+			PF18:          110.0,
+			PMVG12:        70.0,
+		},
+		{
+			// Without CAP, the PMVG is not the discounted PF.
+			Linha:       6,
+			CodigoGGREM: "3",
+			CAP:         false,
+			PF18:        100.0,
+			PMVG18:      100.0,
+		},
+	}
+
+	expected := []Inconsistencia{
+		{Linha: 5, CodigoGGREM: "2", Coluna: PMVG12, Esperado: 63.75, Encontrado: 70, Motivo: "PMVG difere do PF 12% com o desconto do CAP"},
+		{Linha: 5, CodigoGGREM: "2", Coluna: PF12, Esperado: 90, Encontrado: 85, Motivo: "preço menor que o da coluna PF 0%, de alíquota menor"},
+	}
+
+	result := verificaConsistencia(medicamentos, 0.25, 0.01)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %+v, obtido: %+v", expected, result)
+	}
+}
+
+func TestVerificaImpostos(t *testing.T) {
+	medicamentos := []Medicamento{
+		{
+			Linha:                4,
+			CodigoGGREM:          "1",
+			ListaPISCOFINSCodigo: ListaNegativa,
+			PFSemImpostos:        70.0,
+			PF0:                  79.55,
+			PF18:                 100.0,
+			PF18ALC:              85.37,
+			PMVGSemImpostos:      56.0,
+			PMVG18:               85.0,
+		},
+		{
+			Linha:                5.0,
+			CodigoGGREM:          "2",
+			ListaPISCOFINSCodigo: "POSITIVA",
+			PFSemImpostos:        82.0,
+			PF18:                 100.0,
+		},
+		{
+			Linha:         6,
+			CodigoGGREM:   "3",
+			PFSemImpostos: 82.0,
+			PF18:          1.0,
+		},
+	}
+
+	expected := []Inconsistencia{
+		{Linha: 4, CodigoGGREM: "1", Coluna: PMVG18, Esperado: 80, Encontrado: 85, Motivo: "preço difere do PMVG Sem Impostos com ICMS de 18% e PIS/COFINS de 12%"},
+	}
+
+	result := verificaImpostos(medicamentos, impostos.AliquotasPadrao, 0.01)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("esperado: %+v, obtido: %+v", expected, result)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// Consulta holds the criteria of the query command. Empty criteria are
+// ignored and the others must all match.
+type Consulta struct {
+	EAN        string
+	GGREM      string
+	Registro   string
+	CNPJ       string // full CNPJ or its root, with or without punctuation
+	Substancia string // part of the name of one of the ingredients
+	Produto    string // part of the product name
+	Filtro     *Filtro
+}
+
+func (c Consulta) vazia() bool {
+	return c.EAN == "" && c.GGREM == "" && c.Registro == "" && c.CNPJ == "" && c.Substancia == "" && c.Produto == "" && c.Filtro == nil
+}
+
+func somenteDigitos(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func textoCampo(medicamento Medicamento, campo string) string {
+	texto, _ := medicamento[campo].(string)
+	return texto
+}
+
+func contemNormalizado(texto, parte string) bool {
+	return strings.Contains(normalizaTexto(texto), normalizaTexto(parte))
+}
+
+func (c Consulta) aceita(medicamento Medicamento) bool {
+	if c.EAN != "" && textoCampo(medicamento, EAN1) != c.EAN && textoCampo(medicamento, EAN2) != c.EAN && textoCampo(medicamento, EAN3) != c.EAN {
+		return false
+	}
+	if c.GGREM != "" && textoCampo(medicamento, CodigoGGREM) != c.GGREM {
+		return false
+	}
+	if c.Registro != "" && somenteDigitos(textoCampo(medicamento, Registro)) != somenteDigitos(c.Registro) {
+		return false
+	}
+	if c.CNPJ != "" && !strings.HasPrefix(somenteDigitos(textoCampo(medicamento, CNPJ)), somenteDigitos(c.CNPJ)) {
+		return false
+	}
+	if c.Substancia != "" {
+		// Substances are split in the output, but generated files store
+		// them as []any.
+		encontrada := false
+		for _, substancia := range listaTextos(medicamento[Substancias]) {
+			if contemNormalizado(substancia, c.Substancia) {
+				encontrada = true
+				break
+			}
+		}
+		if !encontrada {
+			return false
+		}
+	}
+	if c.Produto != "" && !contemNormalizado(textoCampo(medicamento, Produto), c.Produto) {
+		return false
+	}
+	return c.Filtro.aceita(medicamento)
+}
+
+// listaTextos returns the items of a list field, whether it was built by
+// the parser or decoded from JSON.
+func listaTextos(valor any) []string {
+	switch v := valor.(type) {
+	case []string:
+		return v
+	case []any:
+		var textos []string
+		for _, item := range v {
+			if texto, ok := item.(string); ok {
+				textos = append(textos, texto)
+			}
+		}
+		return textos
+	}
+	return nil
+}
+
+// consultaMedicamentos returns the medicines matching the query, in their
+// original order.
+func consultaMedicamentos(medicamentos []Medicamento, consulta Consulta) []Medicamento {
+	resultado := []Medicamento{}
+	for _, medicamento := range medicamentos {
+		if consulta.aceita(medicamento) {
+			resultado = append(resultado, medicamento)
+		}
+	}
+	return resultado
+}
+
+func formataPreco(valor any) string {
+	preco, ok := valor.(float64)
+	if !ok {
+		return "-"
+	}
+	return strings.Replace(strconv.FormatFloat(preco, 'f', 2, 64), ".", ",", 1)
+}
+
+// writeTabelaConsulta prints the medicines as a table with the PF and PMVG
+// for the given ICMS rate, such as "18%" or "17,5%".
+func writeTabelaConsulta(w io.Writer, medicamentos []Medicamento, icms string) error {
+	colunaPF := "PF " + icms
+	colunaPMVG := "PMVG " + icms
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", CodigoGGREM, EAN1, Produto, Apresentacao, Laboratorio, PFSemImpostos, colunaPF, colunaPMVG)
+	for _, m := range medicamentos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			textoCampo(m, CodigoGGREM), textoCampo(m, EAN1), textoCampo(m, Produto), textoCampo(m, Apresentacao), textoCampo(m, Laboratorio),
+			formataPreco(m[PFSemImpostos]), formataPreco(m[colunaPF]), formataPreco(m[colunaPMVG]))
+	}
+	return tw.Flush()
+}
+
+func runQuery(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	var consulta Consulta
+	flags.StringVar(&consulta.EAN, "ean", "", "Código de barras (EAN 1, 2 ou 3)")
+	flags.StringVar(&consulta.GGREM, "ggrem", "", "Código GGREM")
+	flags.StringVar(&consulta.Registro, "registro", "", "Número do registro na Anvisa")
+	flags.StringVar(&consulta.CNPJ, "cnpj", "", "CNPJ do laboratório, ou a sua raiz")
+	flags.StringVar(&consulta.Substancia, "substancia", "", "Parte do nome de uma das substâncias")
+	flags.StringVar(&consulta.Produto, "produto", "", "Parte do nome do produto")
+	filtro := flags.String("filter", "", "Expressão de filtro adicional")
+	formato := flags.String("format", "tabela", "Formato da saída: tabela ou json")
+	icms := flags.String("icms", "18%", "Alíquota de ICMS dos preços exibidos na tabela")
+	flags.Parse(args)
+
+	if *filtro != "" {
+		var err error
+		if consulta.Filtro, err = parseFiltro(*filtro); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if flags.NArg() != 1 || consulta.vazia() {
+		log.Fatal("Uso: cmed-parser query [--ean|--ggrem|--registro|--cnpj|--substancia|--produto <valor>] [flags] <arquivo.xlsx|json|zip>")
+	}
+	if *formato != "tabela" && *formato != "json" {
+		log.Fatalf("formato desconhecido: %s. Use tabela ou json", *formato)
+	}
+	if !strings.HasSuffix(*icms, "%") {
+		*icms += "%"
+	}
+	if !slices.Contains(cabecalho, "PF "+*icms) {
+		log.Fatalf("alíquota de ICMS desconhecida: %s", *icms)
+	}
+
+	hoje := time.Now()
+	output, err := carregaOutput(flags.Arg(0), hoje, hoje)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	medicamentos := consultaMedicamentos(output.Medicamentos, consulta)
+	if *formato == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(medicamentos)
+	} else {
+		err = writeTabelaConsulta(os.Stdout, medicamentos, *icms)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d medicamentos encontrados", len(medicamentos))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConsultaMedicamentos(t *testing.T) {
+	medicamentos := []Medicamento{
+		{
+			CodigoGGREM: "500101101111417", EAN1: "7891234567890", EAN2: nil, Registro: "1.2345.6789.001-1",
+			CNPJ: "12.345.678/0001-90", Produto: "ALIVIUM", Substancias: []string{"IBUPROFENO"},
+		},
+		{
+			CodigoGGREM: "500101101111418", EAN1: "7891234567891", EAN2: "7891234567899", Registro: "123456789002",
+			CNPJ: "12.345.678/0002-71", Produto: "Dôrflex", Substancias: []string{"DIPIRONA", "ORFENADRINA"},
+		},
+		{
+			// Lists decoded from JSON files are []any.
+			CodigoGGREM: "500101101111419", EAN1: "7890000000001", Registro: nil,
+			CNPJ: "98.765.432/0001-10", Produto: "TYLENOL", Substancias: []any{"PARACETAMOL"},
+		},
+	}
+
+	filtro, err := parseFiltro(`CNPJ ~ "0002"`)
+	if err != nil {
+		t.Fatalf("parseFiltro failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		consulta Consulta
+		expected []string
+	}{
+		{"ean secundário", Consulta{EAN: "7891234567899"}, []string{"500101101111418"}},
+		{"ggrem", Consulta{GGREM: "500101101111419"}, []string{"500101101111419"}},
+		{"registro sem pontuação", Consulta{Registro: "123456789001-1"}, []string{"500101101111417"}},
+		{"raiz do cnpj", Consulta{CNPJ: "12345678"}, []string{"500101101111417", "500101101111418"}},
+		{"cnpj completo", Consulta{CNPJ: "12.345.678/0002-71"}, []string{"500101101111418"}},
+		{"substância", Consulta{Substancia: "orfenad"}, []string{"500101101111418"}},
+		{"substância de json", Consulta{Substancia: "paracetamol"}, []string{"500101101111419"}},
+		{"produto sem acento", Consulta{Produto: "dorflex"}, []string{"500101101111418"}},
+		{"critérios combinados", Consulta{CNPJ: "12345678", Produto: "alivium"}, []string{"500101101111417"}},
+		{"filtro", Consulta{CNPJ: "12345678", Filtro: filtro}, []string{"500101101111418"}},
+		{"nenhum", Consulta{EAN: "0000000000000"}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := []string{}
+			for _, m := range consultaMedicamentos(medicamentos, tc.consulta) {
+				result = append(result, m[CodigoGGREM].(string))
+			}
+			if strings.Join(result, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestWriteTabelaConsulta(t *testing.T) {
+	medicamentos := []Medicamento{
+		{CodigoGGREM: "500101101111417", EAN1: "7891234567890", Produto: "ALIVIUM", Apresentacao: "400 MG COM X 10", Laboratorio: "LAB A", PFSemImpostos: 10.5, PF175: 13.456, PMVG175: nil},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTabelaConsulta(&buf, medicamentos, "17,5%"); err != nil {
+		t.Fatalf("writeTabelaConsulta failed: %v", err)
+	}
+
+	linhas := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(linhas) != 2 {
+		t.Fatalf("esperado: 2 linhas, obtido: %d\n%s", len(linhas), buf.String())
+	}
+	if campos := strings.Fields(linhas[0]); campos[len(campos)-1] != "17,5%" || campos[len(campos)-2] != "PMVG" {
+		t.Errorf("esperado: cabeçalho terminado em PMVG 17,5%%, obtido: %q", linhas[0])
+	}
+	for _, expected := range []string{"500101101111417", "400 MG COM X 10", "10,50", "13,46", "-"} {
+		if !strings.Contains(linhas[1], expected) {
+			t.Errorf("esperado: linha com %q, obtido: %q", expected, linhas[1])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// carregaOutput loads a release from a CMED spreadsheet (.xlsx) or from a
+// file previously generated by the parser (.json or .zip). The dates are
+// only used for spreadsheets, since generated files carry their own.
+func carregaOutput(path string, data, dataAtualizacao time.Time) (Output, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		return processExcelFile(path, data, dataAtualizacao, OpcoesProcessamento{})
+	case ".json":
+		jsonFile, err := os.Open(path)
+		if err != nil {
+			return Output{}, fmt.Errorf("failed to open json file: %w", err)
+		}
+		defer jsonFile.Close()
+
+		var output Output
+		if err := json.NewDecoder(jsonFile).Decode(&output); err != nil {
+			return Output{}, fmt.Errorf("failed to decode json: %w", err)
+		}
+		return output, nil
+	case ".zip":
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return Output{}, fmt.Errorf("failed to open zip file: %w", err)
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if filepath.Ext(f.Name) != ".json" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return Output{}, fmt.Errorf("failed to open json file in zip: %w", err)
+			}
+			defer rc.Close()
+
+			var output Output
+			if err := json.NewDecoder(rc).Decode(&output); err != nil {
+				return Output{}, fmt.Errorf("failed to decode json: %w", err)
+			}
+			return output, nil
+		}
+		return Output{}, fmt.Errorf("no json file found in %s", path)
+	}
+	return Output{}, fmt.Errorf("formato de arquivo não suportado: %s. Use .xlsx, .json ou .zip", path)
+}
+
+// parseDatas validates the --data and --data-atualizacao flags. An empty
+// dataAtualizacao defaults to data.
+func parseDatas(data, dataAtualizacao string) (time.Time, time.Time, error) {
+	if dataAtualizacao == "" {
+		dataAtualizacao = data
+	}
+
+	dataTime, err := time.Parse("2006-01-02", data)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Data inválida: %s. Use o formato AAAA-MM-DD", data)
+	}
+	dataAtualizacaoTime, err := time.Parse("2006-01-02", dataAtualizacao)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Data de atualização inválida: %s. Use o formato AAAA-MM-DD", dataAtualizacao)
+	}
+	return dataTime, dataAtualizacaoTime, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCarregaOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	infilePath := filepath.Join(tempDir, "lista.xlsx")
+
+	expectedOutput := Output{
+		Metadados: Metadados{
+			Data:            "2025-07-03",
+			DataAtualizacao: "2025-07-04",
+			Observacoes:     []string{"Observação 1"},
+		},
+		Medicamentos: []Medicamento{
+			{"SUBSTÂNCIA": "IBUPROFENO", "PF 18%": 12.5},
+		},
+	}
+	if err := writeJSONFile(expectedOutput, infilePath); err != nil {
+		t.Fatalf("writeJSONFile failed: %v", err)
+	}
+	if err := writeZipFile(expectedOutput, infilePath); err != nil {
+		t.Fatalf("writeZipFile failed: %v", err)
+	}
+
+	for _, ext := range []string{".json", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			output, err := carregaOutput(filepath.Join(tempDir, "lista"+ext), time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("carregaOutput failed: %v", err)
+			}
+			if !reflect.DeepEqual(output, expectedOutput) {
+				t.Errorf("unexpected output. got %+v, want %+v", output, expectedOutput)
+			}
+		})
+	}
+
+	if _, err := carregaOutput(filepath.Join(tempDir, "lista.csv"), time.Time{}, time.Time{}); err == nil {
+		t.Error("esperado erro para formato não suportado")
+	}
+}
+
+func TestParseDatas(t *testing.T) {
+	testCases := []struct {
+		name            string
+		data            string
+		dataAtualizacao string
+		expected        [2]string
+		erro            bool
+	}{
+		{"datas válidas", "2025-07-03", "2025-07-04", [2]string{"2025-07-03", "2025-07-04"}, false},
+		{"sem data de atualização", "2025-07-03", "", [2]string{"2025-07-03", "2025-07-03"}, false},
+		{"data inválida", "03/07/2025", "", [2]string{}, true},
+		{"data de atualização inválida", "2025-07-03", "2025-13-01", [2]string{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, dataAtualizacao, err := parseDatas(tc.data, tc.dataAtualizacao)
+			if (err != nil) != tc.erro {
+				t.Fatalf("esperado erro: %v, obtido: %v", tc.erro, err)
+			}
+			if tc.erro {
+				return
+			}
+			result := [2]string{data.Format("2006-01-02"), dataAtualizacao.Format("2006-01-02")}
+			if result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
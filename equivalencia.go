@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// PapelEquivalencia is the role of a product within its equivalence group,
+// derived from TIPO DE PRODUTO.
+type PapelEquivalencia string
+
+const (
+	PapelReferencia PapelEquivalencia = "REFERENCIA"
+	PapelGenerico   PapelEquivalencia = "GENERICO"
+	PapelSimilar    PapelEquivalencia = "SIMILAR"
+	PapelOutro      PapelEquivalencia = "OUTRO"
+)
+
+// papeisEquivalencia maps product types to roles. New drugs are the
+// reference products that generics and similars are compared with.
+var papeisEquivalencia = map[TipoProduto]PapelEquivalencia{
+	TipoProdutoNovo:     PapelReferencia,
+	TipoProdutoGenerico: PapelGenerico,
+	TipoProdutoSimilar:  PapelSimilar,
+}
+
+// MembroEquivalencia is a presentation of an equivalence group.
+type MembroEquivalencia struct {
+	CodigoGGREM      string            `json:"codigoGgrem" xml:"CodigoGGREM"`
+	Produto          string            `json:"produto" xml:"Produto"`
+	Laboratorio      string            `json:"laboratorio" xml:"Laboratorio"`
+	Apresentacao     string            `json:"apresentacao" xml:"Apresentacao"`
+	Papel            PapelEquivalencia `json:"papel" xml:"Papel"`
+	PrecoSemImpostos *float64          `json:"precoSemImpostos" xml:"PrecoSemImpostos"`
+	PrecoUnitario    *float64          `json:"precoUnitario" xml:"PrecoUnitario"`
+}
+
+// GrupoEquivalencia gathers the presentations with the same substances,
+// concentration and pharmaceutical form, which may replace one another.
+// Prices are compared per unit of the pack (PF Sem Impostos divided by the
+// quantity), so packs of different sizes can be compared; members without
+// a price or a known quantity are not ranked.
+type GrupoEquivalencia struct {
+	ID                  int                  `json:"id" xml:"id,attr"`
+	Substancias         []string             `json:"substancias" xml:"Substancias>Substancia"`
+	Concentracao        string               `json:"concentracao" xml:"Concentracao"`
+	Forma               string               `json:"forma" xml:"Forma"`
+	Membros             []MembroEquivalencia `json:"membros" xml:"Membros>Membro"`
+	MaisBarato          *string              `json:"maisBarato" xml:"MaisBarato"`
+	MaisCaro            *string              `json:"maisCaro" xml:"MaisCaro"`
+	PrecoUnitarioMinimo *float64             `json:"precoUnitarioMinimo" xml:"PrecoUnitarioMinimo"`
+	PrecoUnitarioMaximo *float64             `json:"precoUnitarioMaximo" xml:"PrecoUnitarioMaximo"`
+	AmplitudePercentual *float64             `json:"amplitudePercentual" xml:"AmplitudePercentual"`
+}
+
+// grupoParcial is an equivalence group being built, with the medicines that
+// will reference it.
+type grupoParcial struct {
+	grupo        GrupoEquivalencia
+	medicamentos []Medicamento
+}
+
+func substanciasOrdenadas(substancias []string) []string {
+	ordenadas := slices.Clone(substancias)
+	slices.Sort(ordenadas)
+	return ordenadas
+}
+
+func chaveEquivalencia(substancias []string, detalhes DetalhesApresentacao) string {
+	ordenadas := substanciasOrdenadas(substancias)
+	return strings.Join(ordenadas, ";") + "\x00" + strings.ReplaceAll(detalhes.Concentracao, " ", "") + "\x00" + detalhes.Forma
+}
+
+func arredonda(v float64, casas int) float64 {
+	fator := math.Pow(10, float64(casas))
+	return math.Round(v*fator) / fator
+}
+
+// adicionaEquivalencia places the medicine in its equivalence group.
+// Medicines without substances, concentration or form are not grouped.
+func (a *agregador) adicionaEquivalencia(medicamento Medicamento) {
+	medicamento[GrupoEquivalenciaID] = nil
+
+	substancias, _ := medicamento[Substancias].([]string)
+	detalhes, ok := medicamento[ApresentacaoDetalhes].(DetalhesApresentacao)
+	if len(substancias) == 0 || !ok || detalhes.Concentracao == "" || detalhes.Forma == "" {
+		return
+	}
+
+	chave := chaveEquivalencia(substancias, detalhes)
+	parcial, ok := a.equivalencias[chave]
+	if !ok {
+		parcial = &grupoParcial{grupo: GrupoEquivalencia{
+			Substancias:  substanciasOrdenadas(substancias),
+			Concentracao: detalhes.Concentracao,
+			Forma:        detalhes.Forma,
+			Membros:      []MembroEquivalencia{},
+		}}
+		a.equivalencias[chave] = parcial
+		a.ordemEquivalencias = append(a.ordemEquivalencias, chave)
+	}
+
+	papel := PapelOutro
+	if tipo, ok := medicamento[TipoProdutoCodigo].(TipoProduto); ok {
+		if p, ok := papeisEquivalencia[tipo]; ok {
+			papel = p
+		}
+	}
+	membro := MembroEquivalencia{
+		CodigoGGREM:  textoCampo(medicamento, CodigoGGREM),
+		Produto:      textoCampo(medicamento, Produto),
+		Laboratorio:  textoCampo(medicamento, Laboratorio),
+		Apresentacao: textoCampo(medicamento, Apresentacao),
+		Papel:        papel,
+	}
+	if preco, ok := medicamento[PFSemImpostos].(float64); ok {
+		membro.PrecoSemImpostos = &preco
+		if unitario, ok := detalhes.precoUnitario(preco); ok {
+			membro.PrecoUnitario = &unitario
+		}
+	}
+
+	parcial.grupo.Membros = append(parcial.grupo.Membros, membro)
+	parcial.medicamentos = append(parcial.medicamentos, medicamento)
+}
+
+// gruposEquivalencia returns the groups with more than one member, the only
+// ones offering a substitution, numbered in order of first appearance. The
+// medicines of each group are updated to reference it.
+func (a *agregador) gruposEquivalencia() []GrupoEquivalencia {
+	grupos := []GrupoEquivalencia{}
+	for _, chave := range a.ordemEquivalencias {
+		parcial := a.equivalencias[chave]
+		if len(parcial.grupo.Membros) < 2 {
+			continue
+		}
+
+		grupo := parcial.grupo
+		grupo.ID = len(grupos) + 1
+		for _, membro := range grupo.Membros {
+			if membro.PrecoUnitario == nil {
+				continue
+			}
+			if grupo.PrecoUnitarioMinimo == nil || *membro.PrecoUnitario < *grupo.PrecoUnitarioMinimo {
+				grupo.PrecoUnitarioMinimo = membro.PrecoUnitario
+				grupo.MaisBarato = &membro.CodigoGGREM
+			}
+			if grupo.PrecoUnitarioMaximo == nil || *membro.PrecoUnitario > *grupo.PrecoUnitarioMaximo {
+				grupo.PrecoUnitarioMaximo = membro.PrecoUnitario
+				grupo.MaisCaro = &membro.CodigoGGREM
+			}
+		}
+		if grupo.PrecoUnitarioMinimo != nil && *grupo.PrecoUnitarioMinimo > 0 {
+			amplitude := arredonda((*grupo.PrecoUnitarioMaximo / *grupo.PrecoUnitarioMinimo - 1)*100, 2)
+			grupo.AmplitudePercentual = &amplitude
+		}
+
+		for _, medicamento := range parcial.medicamentos {
+			medicamento[GrupoEquivalenciaID] = grupo.ID
+		}
+		grupos = append(grupos, grupo)
+	}
+	return grupos
+}
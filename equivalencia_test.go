@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGruposEquivalencia(t *testing.T) {
+	rows := [][]string{{"Observação"}, cabecalho}
+	linha := func(substancia, ggrem, apresentacao, tipo, preco string) {
+		row := make([]string, len(cabecalho))
+		row[0] = substancia
+		row[3] = ggrem
+		row[9] = apresentacao
+		row[11] = tipo
+		row[13] = preco
+		rows = append(rows, row)
+	}
+	linha("AMOXICILINA", "3", "500 MG CAP DURA CT BL AL X 21", "Genérico", "21,00")
+	linha("AMOXICILINA", "1", "500MG CAP DURA CT 2 BL AL X 15", "Novo", "60,00")
+	linha("AMOXICILINA", "2", "500 MG CAP DURA CT BL AL X 12", "Similar", "18,00")
+	linha("AMOXICILINA", "4", "500 MG CAP DURA CT BL AL X 12", "Biológico", "")
+	linha("AMOXICILINA", "5", "250 MG CAP DURA CT BL AL X 12", "Genérico", "9,00") // alone in its group
+	linha("DIPIRONA", "6", "GOTAS", "Genérico", "5,00")                            // no concentration
+
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(rows, data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+
+	if len(output.GruposEquivalencia) != 1 {
+		t.Fatalf("esperado: 1 grupo, obtido: %+v", output.GruposEquivalencia)
+	}
+	grupo := output.GruposEquivalencia[0]
+	if grupo.ID != 1 || grupo.Concentracao != "500 MG" || grupo.Forma != "CAP DURA" || len(grupo.Membros) != 4 {
+		t.Errorf("esperado: grupo 1 de 500 MG CAP DURA com 4 membros, obtido: %+v", grupo)
+	}
+	if grupo.MaisBarato == nil || *grupo.MaisBarato != "3" || grupo.MaisCaro == nil || *grupo.MaisCaro != "1" {
+		t.Errorf("esperado: 3 e 1, obtido: %v e %v", grupo.MaisBarato, grupo.MaisCaro)
+	}
+	if *grupo.PrecoUnitarioMinimo != 1 || *grupo.PrecoUnitarioMaximo != 2 || *grupo.AmplitudePercentual != 100 {
+		t.Errorf("esperado: 1, 2 e 100%%, obtido: %v, %v e %v%%", *grupo.PrecoUnitarioMinimo, *grupo.PrecoUnitarioMaximo, *grupo.AmplitudePercentual)
+	}
+
+	papeis := make(map[string]PapelEquivalencia)
+	for _, membro := range grupo.Membros {
+		papeis[membro.CodigoGGREM] = membro.Papel
+	}
+	grupos := make(map[string]any)
+	for _, medicamento := range output.Medicamentos {
+		grupos[medicamento[CodigoGGREM].(string)] = medicamento[GrupoEquivalenciaID]
+	}
+
+	testCases := []struct {
+		name  string
+		ggrem string
+		papel PapelEquivalencia
+		grupo any
+	}{
+		{"referência", "1", PapelReferencia, 1},
+		{"similar", "2", PapelSimilar, 1},
+		{"genérico", "3", PapelGenerico, 1},
+		{"outro", "4", PapelOutro, 1},
+		{"sozinho no grupo", "5", "", nil},
+		{"sem concentração", "6", "", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if papeis[tc.ggrem] != tc.papel {
+				t.Errorf("esperado: %q, obtido: %q", tc.papel, papeis[tc.ggrem])
+			}
+			if grupos[tc.ggrem] != tc.grupo {
+				t.Errorf("esperado: %v, obtido: %v", tc.grupo, grupos[tc.ggrem])
+			}
+		})
+	}
+
+	ordenaOutput(&output)
+	var ordem []string
+	for _, membro := range output.GruposEquivalencia[0].Membros {
+		ordem = append(ordem, membro.CodigoGGREM)
+	}
+	if expected := []string{"1", "2", "3", "4"}; !reflect.DeepEqual(ordem, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, ordem)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Identifier systems of the FHIR export. GTIN is the system defined by GS1;
+// the CMED and Anvisa codes have no published naming system, so they are
+// identified under the project namespace.
+const (
+	baseFHIR            = "https://github.com/elvisdiniz/cmed-parser/fhir"
+	sistemaGTIN         = "https://www.gs1.org/gtin"
+	sistemaGGREM        = baseFHIR + "/NamingSystem/ggrem"
+	sistemaRegistro     = baseFHIR + "/NamingSystem/registro-anvisa"
+	sistemaCNPJ         = baseFHIR + "/NamingSystem/cnpj"
+	sistemaColunasPreco = baseFHIR + "/CodeSystem/coluna-preco"
+	sistemaUCUM         = "http://unitsofmeasure.org"
+)
+
+// unidadesUCUM maps the units of DetalhesApresentacao to UCUM codes. Units
+// (UN) have no UCUM code and are written as plain text.
+var unidadesUCUM = map[string]string{
+	"ML": "mL",
+	"G":  "g",
+}
+
+type fhirBundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Entry        []fhirEntrada `json:"entry"`
+}
+
+// fhirEntrada is an entry of the Bundle. Its fullUrl is the RESTful URL of
+// the resource under baseFHIR, against which the relative references
+// between resources of the Bundle, such as "Medication/{id}", resolve.
+type fhirEntrada struct {
+	FullURL  string `json:"fullUrl"`
+	Resource any    `json:"resource"`
+}
+
+func entradaFHIR(tipo, id string, recurso any) fhirEntrada {
+	return fhirEntrada{FullURL: baseFHIR + "/" + tipo + "/" + id, Resource: recurso}
+}
+
+type fhirIdentificador struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+type fhirCoding struct {
+	System string `json:"system"`
+	Code   string `json:"code"`
+}
+
+type fhirConceito struct {
+	Coding []fhirCoding `json:"coding,omitempty"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type fhirReferencia struct {
+	Reference  string             `json:"reference,omitempty"`
+	Identifier *fhirIdentificador `json:"identifier,omitempty"`
+	Display    string             `json:"display,omitempty"`
+}
+
+type fhirQuantidade struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type fhirRazao struct {
+	Numerator   fhirQuantidade `json:"numerator"`
+	Denominator fhirQuantidade `json:"denominator"`
+}
+
+type fhirIngrediente struct {
+	ItemCodeableConcept fhirConceito `json:"itemCodeableConcept"`
+	IsActive            bool         `json:"isActive"`
+}
+
+type fhirMedication struct {
+	ResourceType string              `json:"resourceType"`
+	ID           string              `json:"id"`
+	Identifier   []fhirIdentificador `json:"identifier,omitempty"`
+	Code         *fhirConceito       `json:"code,omitempty"`
+	Status       string              `json:"status"`
+	Manufacturer *fhirReferencia     `json:"manufacturer,omitempty"`
+	Form         *fhirConceito       `json:"form,omitempty"`
+	Amount       *fhirRazao          `json:"amount,omitempty"`
+	Ingredient   []fhirIngrediente   `json:"ingredient,omitempty"`
+}
+
+type fhirPeriodo struct {
+	Start string `json:"start,omitempty"`
+}
+
+type fhirDinheiro struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency"`
+}
+
+type fhirComponentePreco struct {
+	Type   string       `json:"type"`
+	Code   fhirConceito `json:"code"`
+	Amount fhirDinheiro `json:"amount"`
+}
+
+type fhirGrupoPropriedades struct {
+	PriceComponent []fhirComponentePreco `json:"priceComponent"`
+}
+
+type fhirChargeItemDefinition struct {
+	ResourceType    string                  `json:"resourceType"`
+	ID              string                  `json:"id"`
+	URL             string                  `json:"url"`
+	Title           string                  `json:"title,omitempty"`
+	Status          string                  `json:"status"`
+	Instance        []fhirReferencia        `json:"instance"`
+	EffectivePeriod *fhirPeriodo            `json:"effectivePeriod,omitempty"`
+	PropertyGroup   []fhirGrupoPropriedades `json:"propertyGroup"`
+}
+
+// idFHIR returns the resource id of a medicine: its GGREM code or, without
+// one, its spreadsheet row.
+func idFHIR(medicamento Medicamento, i int) string {
+	if ggrem := textoCampo(medicamento, CodigoGGREM); ggrem != "" {
+		return ggrem
+	}
+	if linha, ok := medicamento[Linha].(int); ok {
+		return fmt.Sprintf("linha-%d", linha)
+	}
+	return fmt.Sprintf("medicamento-%d", i+1)
+}
+
+// descricaoFHIR joins the product name and the presentation.
+func descricaoFHIR(medicamento Medicamento) string {
+	return strings.TrimSpace(textoCampo(medicamento, Produto) + " " + textoCampo(medicamento, Apresentacao))
+}
+
+// medicationFHIR converts a medicine to a FHIR R4 Medication. Fields removed
+// by the projection are left out of the resource.
+func medicationFHIR(medicamento Medicamento, id string) fhirMedication {
+	medication := fhirMedication{ResourceType: "Medication", ID: id, Status: "active"}
+
+	if ggrem := textoCampo(medicamento, CodigoGGREM); ggrem != "" {
+		medication.Identifier = append(medication.Identifier, fhirIdentificador{sistemaGGREM, ggrem})
+	}
+	if registro := textoCampo(medicamento, Registro); registro != "" {
+		medication.Identifier = append(medication.Identifier, fhirIdentificador{sistemaRegistro, registro})
+	}
+
+	codigo := fhirConceito{Text: descricaoFHIR(medicamento)}
+	for _, ean := range []string{EAN1, EAN2, EAN3} {
+		if gtin := textoCampo(medicamento, ean); gtin != "" {
+			codigo.Coding = append(codigo.Coding, fhirCoding{sistemaGTIN, gtin})
+		}
+	}
+	if len(codigo.Coding) > 0 || codigo.Text != "" {
+		medication.Code = &codigo
+	}
+
+	if cnpj, nome := textoCampo(medicamento, CNPJ), textoCampo(medicamento, Laboratorio); cnpj != "" || nome != "" {
+		medication.Manufacturer = &fhirReferencia{Display: nome}
+		if cnpj != "" {
+			medication.Manufacturer.Identifier = &fhirIdentificador{sistemaCNPJ, somenteDigitos(cnpj)}
+		}
+	}
+
+	detalhes, temDetalhes := medicamento[ApresentacaoDetalhes].(DetalhesApresentacao)
+	if temDetalhes && detalhes.Forma != "" {
+		medication.Form = &fhirConceito{Text: detalhes.Forma}
+	} else if apresentacao := textoCampo(medicamento, Apresentacao); apresentacao != "" {
+		medication.Form = &fhirConceito{Text: apresentacao}
+	}
+	if temDetalhes && detalhes.Quantidade != nil {
+		quantidade := fhirQuantidade{Value: *detalhes.Quantidade, Unit: detalhes.Unidade}
+		if codigo, ok := unidadesUCUM[detalhes.Unidade]; ok {
+			quantidade.System, quantidade.Code = sistemaUCUM, codigo
+		}
+		medication.Amount = &fhirRazao{Numerator: quantidade, Denominator: fhirQuantidade{Value: 1}}
+	}
+
+	substancias, _ := medicamento[Substancias].([]string)
+	if len(substancias) == 0 {
+		if substancia := textoCampo(medicamento, PrincipioAtivo); substancia != "" {
+			substancias = []string{substancia}
+		}
+	}
+	for _, substancia := range substancias {
+		medication.Ingredient = append(medication.Ingredient, fhirIngrediente{
+			ItemCodeableConcept: fhirConceito{Text: substancia},
+			IsActive:            true,
+		})
+	}
+
+	return medication
+}
+
+// chargeItemDefinitionFHIR lists the PF and PMVG prices of a medicine, one
+// property group per price column, or reports false when it has none.
+func chargeItemDefinitionFHIR(medicamento Medicamento, id, data string) (fhirChargeItemDefinition, bool) {
+	definicao := fhirChargeItemDefinition{
+		ResourceType:  "ChargeItemDefinition",
+		ID:            id,
+		URL:           baseFHIR + "/ChargeItemDefinition/" + id,
+		Title:         descricaoFHIR(medicamento),
+		Status:        "active",
+		Instance:      []fhirReferencia{{Reference: "Medication/" + id}},
+		PropertyGroup: []fhirGrupoPropriedades{},
+	}
+	if data != "" {
+		definicao.EffectivePeriod = &fhirPeriodo{Start: data}
+	}
+
+	for _, header := range cabecalho {
+		preco, ok := medicamento[header].(float64)
+		if !ok || !ehColunaPreco(header) {
+			continue
+		}
+		definicao.PropertyGroup = append(definicao.PropertyGroup, fhirGrupoPropriedades{
+			PriceComponent: []fhirComponentePreco{{
+				Type:   "base",
+				Code:   fhirConceito{Coding: []fhirCoding{{sistemaColunasPreco, header}}, Text: header},
+				Amount: fhirDinheiro{Value: preco, Currency: "BRL"},
+			}},
+		})
+	}
+	return definicao, len(definicao.PropertyGroup) > 0
+}
+
+// bundleFHIR converts the medicines of the output to a FHIR R4 collection
+// Bundle of Medication resources, each followed by its
+// ChargeItemDefinition when prices are requested.
+func bundleFHIR(output Output, precos bool) fhirBundle {
+	bundle := fhirBundle{
+		ResourceType: "Bundle",
+		Type:         "collection",
+		Timestamp:    output.Metadados.GeradoEm,
+		Entry:        []fhirEntrada{},
+	}
+	for i, medicamento := range output.Medicamentos {
+		id := idFHIR(medicamento, i)
+		bundle.Entry = append(bundle.Entry, entradaFHIR("Medication", id, medicationFHIR(medicamento, id)))
+		if !precos {
+			continue
+		}
+		if definicao, ok := chargeItemDefinitionFHIR(medicamento, id, output.Metadados.Data); ok {
+			bundle.Entry = append(bundle.Entry, entradaFHIR("ChargeItemDefinition", id, definicao))
+		}
+	}
+	return bundle
+}
+
+func codificaFHIR(w io.Writer, output Output, opcoes OpcoesSaida) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundleFHIR(output, opcoes.PrecosFHIR)); err != nil {
+		return fmt.Errorf("failed to encode fhir bundle: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleFHIR(t *testing.T) {
+	quantidade := 21.0
+	output := Output{
+		Metadados: Metadados{Data: "2025-07-03", GeradoEm: "2025-07-03T10:00:00-03:00"},
+		Medicamentos: []Medicamento{
+			{
+				CodigoGGREM: "500101101111417", Registro: "1.2345.6789.001-1", EAN1: "7891234567890", EAN2: nil,
+				Produto: "AMOXIL", Apresentacao: "500 MG CAP DURA CT BL AL X 21",
+				CNPJ: "12.345.678/0001-90", Laboratorio: "LAB A", Substancias: []string{"AMOXICILINA"},
+				ApresentacaoDetalhes: DetalhesApresentacao{Concentracao: "500 MG", Forma: "CAP DURA", Quantidade: &quantidade, Unidade: "UN"},
+				PFSemImpostos:        21.0, PF18: 27.5, PMVG18: nil,
+				Linha: 4,
+			},
+			{PrincipioAtivo: "DIPIRONA", Apresentacao: "GOTAS", Linha: 5},
+		},
+	}
+
+	bundle := bundleFHIR(output, false)
+	if bundle.ResourceType != "Bundle" || bundle.Type != "collection" || bundle.Timestamp != output.Metadados.GeradoEm {
+		t.Errorf("esperado: Bundle collection de %s, obtido: %+v", output.Metadados.GeradoEm, bundle)
+	}
+	if len(bundle.Entry) != 2 {
+		t.Fatalf("esperado: 2 entradas, obtido: %d", len(bundle.Entry))
+	}
+
+	medication := bundle.Entry[0].Resource.(fhirMedication)
+	if medication.ID != "500101101111417" || medication.Status != "active" {
+		t.Errorf("esperado: Medication 500101101111417 ativo, obtido: %+v", medication)
+	}
+	if len(medication.Identifier) != 2 || medication.Identifier[1] != (fhirIdentificador{sistemaRegistro, "1.2345.6789.001-1"}) {
+		t.Errorf("esperado: GGREM e registro, obtido: %+v", medication.Identifier)
+	}
+	if len(medication.Code.Coding) != 1 || medication.Code.Coding[0] != (fhirCoding{sistemaGTIN, "7891234567890"}) || medication.Code.Text != "AMOXIL 500 MG CAP DURA CT BL AL X 21" {
+		t.Errorf("esperado: GTIN 7891234567890 e descrição, obtido: %+v", medication.Code)
+	}
+	if medication.Manufacturer.Display != "LAB A" || medication.Manufacturer.Identifier.Value != "12345678000190" {
+		t.Errorf("esperado: LAB A 12345678000190, obtido: %+v", medication.Manufacturer)
+	}
+	if medication.Form.Text != "CAP DURA" || medication.Amount.Numerator.Value != 21 || medication.Amount.Numerator.Unit != "UN" {
+		t.Errorf("esperado: CAP DURA com 21 UN, obtido: %+v %+v", medication.Form, medication.Amount)
+	}
+	if len(medication.Ingredient) != 1 || medication.Ingredient[0].ItemCodeableConcept.Text != "AMOXICILINA" {
+		t.Errorf("esperado: AMOXICILINA, obtido: %+v", medication.Ingredient)
+	}
+
+	semCodigo := bundle.Entry[1].Resource.(fhirMedication)
+	if semCodigo.ID != "linha-5" || semCodigo.Form.Text != "GOTAS" || semCodigo.Code.Text != "GOTAS" || semCodigo.Ingredient[0].ItemCodeableConcept.Text != "DIPIRONA" {
+		t.Errorf("esperado: linha-5 GOTAS com DIPIRONA, obtido: %+v", semCodigo)
+	}
+
+	bundle = bundleFHIR(output, true)
+	if len(bundle.Entry) != 3 {
+		t.Fatalf("esperado: 3 entradas, obtido: %d", len(bundle.Entry))
+	}
+	definicao := bundle.Entry[1].Resource.(fhirChargeItemDefinition)
+	if definicao.Instance[0].Reference != "Medication/500101101111417" || definicao.EffectivePeriod.Start != "2025-07-03" {
+		t.Errorf("esperado: instância Medication/500101101111417 a partir de 2025-07-03, obtido: %+v", definicao)
+	}
+	// The relative reference of the instance resolves to the Medication
+	// entry through the base of the fullUrl of the definition.
+	testCases := []struct {
+		name     string
+		entrada  fhirEntrada
+		expected string
+	}{
+		{"medication", bundle.Entry[0], baseFHIR + "/Medication/500101101111417"},
+		{"charge item definition", bundle.Entry[1], baseFHIR + "/ChargeItemDefinition/500101101111417"},
+		{"medication sem códigos", bundle.Entry[2], baseFHIR + "/Medication/linha-5"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.entrada.FullURL != tc.expected {
+				t.Errorf("esperado: %s, obtido: %s", tc.expected, tc.entrada.FullURL)
+			}
+		})
+	}
+	base := strings.TrimSuffix(bundle.Entry[1].FullURL, "ChargeItemDefinition/"+definicao.ID)
+	if result := base + definicao.Instance[0].Reference; result != bundle.Entry[0].FullURL {
+		t.Errorf("esperado: %s, obtido: %s", bundle.Entry[0].FullURL, result)
+	}
+	if len(definicao.PropertyGroup) != 2 {
+		t.Fatalf("esperado: 2 preços, obtido: %+v", definicao.PropertyGroup)
+	}
+	preco := definicao.PropertyGroup[1].PriceComponent[0]
+	if preco.Code.Text != PF18 || preco.Amount != (fhirDinheiro{27.5, "BRL"}) {
+		t.Errorf("esperado: %s de 27.5 BRL, obtido: %+v", PF18, preco)
+	}
+}
+
+func TestEscreveSaidaFHIR(t *testing.T) {
+	rows := linhasSinteticas(3)
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(rows, data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+
+	infilePath := filepath.Join(t.TempDir(), "lista.xlsx")
+	opcoes := OpcoesSaida{Canonico: true, Formato: FormatoFHIR, PrecosFHIR: true}
+	if err := escreveSaida(output, infilePath, opcoes); err != nil {
+		t.Fatalf("escreveSaida failed: %v", err)
+	}
+
+	conteudo, err := os.ReadFile(filepath.Join(filepath.Dir(infilePath), "lista.fhir.json"))
+	if err != nil {
+		t.Fatalf("failed to read fhir file: %v", err)
+	}
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource map[string]any `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(conteudo, &bundle); err != nil {
+		t.Fatalf("failed to decode fhir file: %v", err)
+	}
+	if bundle.ResourceType != "Bundle" || len(bundle.Entry) != 6 {
+		t.Fatalf("esperado: Bundle com 6 entradas, obtido: %s com %d", bundle.ResourceType, len(bundle.Entry))
+	}
+	for i, entrada := range bundle.Entry {
+		expected := []string{"Medication", "ChargeItemDefinition"}[i%2]
+		if entrada.Resource["resourceType"] != expected {
+			t.Errorf("entrada %d: esperado: %s, obtido: %v", i, expected, entrada.Resource["resourceType"])
+		}
+	}
+}
+
+func TestFlagsSaidaFormato(t *testing.T) {
+	testCases := []struct {
+		name     string
+		formato  string
+		expected bool
+	}{
+		{"json", "json", true},
+		{"fhir", "fhir", true},
+		{"desconhecido", "csv", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := registraFlagsSaida(flagSet)
+			if err := flagSet.Parse([]string{"--format", tc.formato}); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			_, err := flags.opcoes()
+			if result := err == nil; result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v (%v)", tc.expected, result, err)
+			}
+		})
+	}
+}
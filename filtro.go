@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filtro selects the medicines kept in the output. Expressions compare a
+// field with literals:
+//
+//	TARJA == "Tarja Preta" && PF18 > 100
+//	CNPJ in ["12.345.678/0001-90", "98.765.432/0001-10"]
+//	SUBSTÂNCIA ~ "AMOXICILINA" || !(`PF 17,5%` <= 10)
+//
+// Fields are named like in --columns; names with spaces or symbols are
+// quoted with backticks. Strings are compared ignoring case and accents,
+// "~" tests whether the field contains the text, and list fields such as
+// substancias match when any of their items does.
+type Filtro struct {
+	expressao string
+	raiz      noFiltro
+}
+
+type noFiltro interface {
+	avalia(medicamento Medicamento) bool
+}
+
+type filtroE struct{ esquerda, direita noFiltro }
+
+func (f filtroE) avalia(m Medicamento) bool { return f.esquerda.avalia(m) && f.direita.avalia(m) }
+
+type filtroOu struct{ esquerda, direita noFiltro }
+
+func (f filtroOu) avalia(m Medicamento) bool { return f.esquerda.avalia(m) || f.direita.avalia(m) }
+
+type filtroNao struct{ operando noFiltro }
+
+func (f filtroNao) avalia(m Medicamento) bool { return !f.operando.avalia(m) }
+
+type comparacao struct {
+	campo    string
+	operador string
+	valores  []any // a single literal, or the items of an "in" list
+}
+
+func (c comparacao) avalia(m Medicamento) bool {
+	// A list field matches when any of its items does. Lists loaded from
+	// JSON files are []any rather than []string.
+	switch itens := m[c.campo].(type) {
+	case []string:
+		return slices.ContainsFunc(itens, func(item string) bool { return c.comparaValor(item) })
+	case []any:
+		return slices.ContainsFunc(itens, func(item any) bool { return c.comparaValor(valorFiltro(item)) })
+	}
+	return c.comparaValor(valorFiltro(m[c.campo]))
+}
+
+func (c comparacao) comparaValor(valor any) bool {
+	switch c.operador {
+	case "==":
+		return iguais(valor, c.valores[0])
+	case "!=":
+		return !iguais(valor, c.valores[0])
+	case "in":
+		for _, literal := range c.valores {
+			if iguais(valor, literal) {
+				return true
+			}
+		}
+		return false
+	case "~":
+		texto, ok := valor.(string)
+		return ok && strings.Contains(normalizaTexto(texto), normalizaTexto(c.valores[0].(string)))
+	}
+
+	numero, ok := valor.(float64)
+	if !ok {
+		return false
+	}
+	limite := c.valores[0].(float64)
+	switch c.operador {
+	case "<":
+		return numero < limite
+	case "<=":
+		return numero <= limite
+	case ">":
+		return numero > limite
+	case ">=":
+		return numero >= limite
+	}
+	return false
+}
+
+// valorFiltro converts a medicine field to the types of the filter
+// literals: string, float64, bool or nil.
+func valorFiltro(valor any) any {
+	switch v := valor.(type) {
+	case nil, string, float64, bool:
+		return v
+	case int:
+		return float64(v)
+	case ClassificacaoTerapeutica:
+		return v.Codigo
+	case map[string]any: // a classification loaded from JSON
+		return valorFiltro(v["codigo"])
+	}
+	if rv := reflect.ValueOf(valor); rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	return fmt.Sprint(valor)
+}
+
+func iguais(valor, literal any) bool {
+	if texto, ok := valor.(string); ok {
+		if literalTexto, ok := literal.(string); ok {
+			return normalizaTexto(texto) == normalizaTexto(literalTexto)
+		}
+		return false
+	}
+	return valor == literal
+}
+
+// camposAgregados are assigned by the aggregator, after the workers have
+// applied the filter, so a filter on them would never match while
+// processing a spreadsheet.
+var camposAgregados = []string{ApresentacaoID, GrupoEquivalenciaID}
+
+// parseFiltro compiles a filter expression.
+func parseFiltro(expressao string) (*Filtro, error) {
+	return compilaFiltro(expressao, nil)
+}
+
+// parseFiltroProcessamento compiles a filter applied while processing a
+// spreadsheet, rejecting the fields it cannot see yet.
+func parseFiltroProcessamento(expressao string) (*Filtro, error) {
+	return compilaFiltro(expressao, camposAgregados)
+}
+
+func compilaFiltro(expressao string, indisponiveis []string) (*Filtro, error) {
+	tokens, err := tokenizaFiltro(expressao)
+	if err != nil {
+		return nil, err
+	}
+	p := &parserFiltro{tokens: tokens, indisponiveis: indisponiveis}
+	raiz, err := p.ou()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.atual(); t.tipo != tokenFim {
+		return nil, fmt.Errorf("filtro inválido na posição %d: '%s' inesperado", t.posicao+1, t.texto)
+	}
+	return &Filtro{expressao: expressao, raiz: raiz}, nil
+}
+
+// aceita reports whether the medicine is kept. A nil filter keeps all of
+// them.
+func (f *Filtro) aceita(medicamento Medicamento) bool {
+	return f == nil || f.raiz.avalia(medicamento)
+}
+
+func (f *Filtro) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expressao
+}
+
+type tipoToken int
+
+const (
+	tokenFim tipoToken = iota
+	tokenCampo
+	tokenTexto
+	tokenNumero
+	tokenOperador
+)
+
+type token struct {
+	tipo    tipoToken
+	texto   string
+	posicao int
+}
+
+// operadoresFiltro lists the operators and punctuation, two-character ones
+// first so that "<=" is not read as "<".
+var operadoresFiltro = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "~", "!", "(", ")", "[", "]", ","}
+
+var operadoresComparacao = []string{"==", "!=", "<", "<=", ">", ">=", "~"}
+
+func tokenizaFiltro(expressao string) ([]token, error) {
+	var tokens []token
+	runas := []rune(expressao)
+	for i := 0; i < len(runas); {
+		r := runas[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '`':
+			var texto strings.Builder
+			j := i + 1
+			for ; j < len(runas) && runas[j] != r; j++ {
+				if r == '"' && runas[j] == '\\' && j+1 < len(runas) {
+					j++
+				}
+				texto.WriteRune(runas[j])
+			}
+			if j == len(runas) {
+				return nil, fmt.Errorf("filtro inválido na posição %d: texto sem fim", i+1)
+			}
+			tipo := tokenTexto
+			if r == '`' {
+				tipo = tokenCampo
+			}
+			tokens = append(tokens, token{tipo, texto.String(), i})
+			i = j + 1
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runas) && unicode.IsDigit(runas[i+1])):
+			j := i + 1
+			for j < len(runas) && (unicode.IsDigit(runas[j]) || runas[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumero, string(runas[i:j]), i})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runas) && (unicode.IsLetter(runas[j]) || unicode.IsDigit(runas[j]) || runas[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{tokenCampo, string(runas[i:j]), i})
+			i = j
+
+		default:
+			operador := ""
+			for _, op := range operadoresFiltro {
+				if strings.HasPrefix(string(runas[i:]), op) {
+					operador = op
+					break
+				}
+			}
+			if operador == "" {
+				return nil, fmt.Errorf("filtro inválido na posição %d: caractere '%c' inesperado", i+1, r)
+			}
+			tokens = append(tokens, token{tokenOperador, operador, i})
+			i += len([]rune(operador))
+		}
+	}
+	return append(tokens, token{tokenFim, "fim do filtro", len(runas)}), nil
+}
+
+type parserFiltro struct {
+	tokens        []token
+	pos           int
+	indisponiveis []string // fields that cannot be filtered on
+}
+
+func (p *parserFiltro) atual() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parserFiltro) consome(operador string) bool {
+	if t := p.atual(); t.tipo == tokenOperador && t.texto == operador {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parserFiltro) erro(esperado string) error {
+	t := p.atual()
+	return fmt.Errorf("filtro inválido na posição %d: esperado %s, encontrado '%s'", t.posicao+1, esperado, t.texto)
+}
+
+func (p *parserFiltro) ou() (noFiltro, error) {
+	esquerda, err := p.e()
+	if err != nil {
+		return nil, err
+	}
+	for p.consome("||") {
+		direita, err := p.e()
+		if err != nil {
+			return nil, err
+		}
+		esquerda = filtroOu{esquerda, direita}
+	}
+	return esquerda, nil
+}
+
+func (p *parserFiltro) e() (noFiltro, error) {
+	esquerda, err := p.unario()
+	if err != nil {
+		return nil, err
+	}
+	for p.consome("&&") {
+		direita, err := p.unario()
+		if err != nil {
+			return nil, err
+		}
+		esquerda = filtroE{esquerda, direita}
+	}
+	return esquerda, nil
+}
+
+func (p *parserFiltro) unario() (noFiltro, error) {
+	if p.consome("!") {
+		operando, err := p.unario()
+		if err != nil {
+			return nil, err
+		}
+		return filtroNao{operando}, nil
+	}
+	if p.consome("(") {
+		expressao, err := p.ou()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consome(")") {
+			return nil, p.erro("')'")
+		}
+		return expressao, nil
+	}
+	return p.comparacao()
+}
+
+func (p *parserFiltro) comparacao() (noFiltro, error) {
+	t := p.atual()
+	if t.tipo != tokenCampo {
+		return nil, p.erro("um campo")
+	}
+	campo, ok := resolveColuna(t.texto)
+	if !ok {
+		return nil, fmt.Errorf("filtro inválido na posição %d: campo desconhecido '%s'", t.posicao+1, t.texto)
+	}
+	if slices.Contains(p.indisponiveis, campo) {
+		return nil, fmt.Errorf("filtro inválido na posição %d: o campo '%s' é atribuído após o filtro e não pode ser filtrado no processamento", t.posicao+1, t.texto)
+	}
+	p.pos++
+
+	if t := p.atual(); t.tipo == tokenCampo && t.texto == "in" {
+		p.pos++
+		if !p.consome("[") {
+			return nil, p.erro("'['")
+		}
+		var valores []any
+		for !p.consome("]") {
+			if len(valores) > 0 && !p.consome(",") {
+				return nil, p.erro("',' ou ']'")
+			}
+			valor, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			valores = append(valores, valor)
+		}
+		return comparacao{campo, "in", valores}, nil
+	}
+
+	operador := p.atual()
+	if operador.tipo != tokenOperador || !slices.Contains(operadoresComparacao, operador.texto) {
+		return nil, p.erro("um operador de comparação")
+	}
+	p.pos++
+
+	literal := p.atual()
+	valor, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	switch operador.texto {
+	case "<", "<=", ">", ">=":
+		if _, ok := valor.(float64); !ok {
+			return nil, fmt.Errorf("filtro inválido na posição %d: o operador %s exige um número", literal.posicao+1, operador.texto)
+		}
+	case "~":
+		if _, ok := valor.(string); !ok {
+			return nil, fmt.Errorf("filtro inválido na posição %d: o operador ~ exige um texto", literal.posicao+1)
+		}
+	}
+	return comparacao{campo, operador.texto, []any{valor}}, nil
+}
+
+func (p *parserFiltro) literal() (any, error) {
+	t := p.atual()
+	switch {
+	case t.tipo == tokenTexto:
+		p.pos++
+		return t.texto, nil
+	case t.tipo == tokenNumero:
+		numero, err := strconv.ParseFloat(t.texto, 64)
+		if err != nil {
+			return nil, fmt.Errorf("filtro inválido na posição %d: número '%s' inválido", t.posicao+1, t.texto)
+		}
+		p.pos++
+		return numero, nil
+	case t.tipo == tokenCampo && (t.texto == "true" || t.texto == "false"):
+		p.pos++
+		return t.texto == "true", nil
+	case t.tipo == tokenCampo && t.texto == "null":
+		p.pos++
+		return nil, nil
+	}
+	return nil, p.erro("um valor")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiltro(t *testing.T) {
+	medicamento := Medicamento{
+		PrincipioAtivo:    "AMOXICILINA;CLAVULANATO DE POTÁSSIO",
+		Substancias:       []string{"AMOXICILINA", "CLAVULANATO DE POTASSIO"},
+		CNPJ:              "12.345.678/0001-90",
+		Tarja:             "Tarja Vermelha",
+		TarjaCodigo:       TarjaVermelha,
+		PF18:              120.5,
+		PF175:             98.0,
+		EAN2:              nil,
+		CAP:               true,
+		Classificacao:     ClassificacaoTerapeutica{Codigo: "J1C"},
+		ClasseTerapeutica: "J1C - PENICILINAS DE AMPLO ESPECTRO",
+		Linha:             7,
+	}
+
+	testCases := []struct {
+		expressao string
+		expected  bool
+	}{
+		{`TARJA == "Tarja Vermelha"`, true},
+		{`TARJA == "TARJA vermelha"`, true},
+		{`TARJA != "Tarja Preta"`, true},
+		{`tarja == "VERMELHA"`, true},
+		{`PF18 > 100`, true},
+		{`PF18 >= 120.5 && PF18 <= 120.5`, true},
+		{`PF18 < 100`, false},
+		{"`PF 17,5%` < 100", true},
+		{`EAN2 == null`, true},
+		{`EAN2 > 0`, false},
+		{`CAP == true`, true},
+		{`CNPJ in ["98.765.432/0001-10", "12.345.678/0001-90"]`, true},
+		{`CNPJ in []`, false},
+		{`SUBSTÂNCIA ~ "potassio"`, true},
+		{`substancias == "AMOXICILINA"`, true},
+		{`substancias ~ "IBUPROFENO"`, false},
+		{`classificacaoTerapeutica == "J1C"`, true},
+		{`linha == 7`, true},
+		{`TARJA == "Tarja Preta" || PF18 > 100`, true},
+		{`!(TARJA == "Tarja Preta") && !PF18 < 100`, true},
+		{`TARJA == "Tarja Preta" && PF18 > 100 || CAP == true`, true},
+		{`TARJA == "Tarja Preta" && (PF18 > 100 || CAP == true)`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expressao, func(t *testing.T) {
+			filtro, err := parseFiltro(tc.expressao)
+			if err != nil {
+				t.Fatalf("parseFiltro failed: %v", err)
+			}
+			if result := filtro.aceita(medicamento); result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+
+	var nenhum *Filtro
+	if !nenhum.aceita(medicamento) || nenhum.String() != "" {
+		t.Errorf("esperado: filtro nulo aceita tudo, obtido: %v, %q", nenhum.aceita(medicamento), nenhum.String())
+	}
+}
+
+func TestParseFiltroErros(t *testing.T) {
+	testCases := []struct {
+		expressao string
+		expected  string
+	}{
+		{`PRECO > 10`, "filtro inválido na posição 1: campo desconhecido 'PRECO'"},
+		{`PF18 > "caro"`, "filtro inválido na posição 8: o operador > exige um número"},
+		{`TARJA ~ 10`, "filtro inválido na posição 9: o operador ~ exige um texto"},
+		{`TARJA = "Tarja Preta"`, "filtro inválido na posição 7: caractere '=' inesperado"},
+		{`TARJA == "Tarja Preta`, "filtro inválido na posição 10: texto sem fim"},
+		{`(PF18 > 10`, "filtro inválido na posição 11: esperado ')', encontrado 'fim do filtro'"},
+		{`CNPJ in ["1" "2"]`, "filtro inválido na posição 14: esperado ',' ou ']', encontrado '2'"},
+		{`PF18 > 10 PF0 < 5`, "filtro inválido na posição 11: 'PF0' inesperado"},
+		{`PF18`, "filtro inválido na posição 5: esperado um operador de comparação, encontrado 'fim do filtro'"},
+		{`10 < PF18`, "filtro inválido na posição 1: esperado um campo, encontrado '10'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expressao, func(t *testing.T) {
+			_, err := parseFiltro(tc.expressao)
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("esperado: %q, obtido: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestProcessRowsFiltro(t *testing.T) {
+	rows := linhasSinteticas(600)
+	rows[2+10][72] = "Tarja Preta"
+	rows[2+500][72] = "Tarja Preta"
+	rows[2+500][11] = "Inédito"
+
+	filtro, err := parseFiltro(`TARJA == "Tarja Preta"`)
+	if err != nil {
+		t.Fatalf("parseFiltro failed: %v", err)
+	}
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(rows, data, data, OpcoesProcessamento{Workers: 3, Filtro: filtro})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+
+	if len(output.Medicamentos) != 2 {
+		t.Fatalf("esperado: 2 medicamentos, obtido: %d", len(output.Medicamentos))
+	}
+	// The rows filtered out are still counted as read.
+	if output.Metadados.Linhas != 600 {
+		t.Errorf("esperado: %d, obtido: %d", 600, output.Metadados.Linhas)
+	}
+	for i, linha := range []int{13, 503} {
+		if output.Medicamentos[i][Linha] != linha {
+			t.Errorf("esperado: %d, obtido: %v", linha, output.Medicamentos[i][Linha])
+		}
+	}
+	if expected := `TARJA == "Tarja Preta"`; output.Metadados.Filtro != expected {
+		t.Errorf("esperado: %q, obtido: %q", expected, output.Metadados.Filtro)
+	}
+	if len(output.Apresentacoes) != 2 {
+		t.Errorf("esperado: 2 apresentações, obtido: %d", len(output.Apresentacoes))
+	}
+	expectedAviso := "valor desconhecido na linha 503 e coluna TIPO DE PRODUTO (STATUS DO PRODUTO): 'Inédito'"
+	if len(output.Avisos) == 0 || output.Avisos[0] != expectedAviso {
+		t.Errorf("esperado: %q, obtido: %q", expectedAviso, output.Avisos[:min(1, len(output.Avisos))])
+	}
+}
+
+func TestFiltroCamposAgregados(t *testing.T) {
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	rows := linhasSinteticas(30)
+	// Two equivalent presentations, so that an equivalence group exists.
+	rows[2+1][0], rows[2+1][9] = rows[2][0], rows[2][9]
+	output, err := processRows(rows, data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		expressao string
+		expected  string
+	}{
+		{
+			name:      "apresentação",
+			expressao: `apresentacaoId == 1`,
+			expected:  "filtro inválido na posição 1: o campo 'apresentacaoId' é atribuído após o filtro e não pode ser filtrado no processamento",
+		},
+		{
+			name:      "grupo de equivalência",
+			expressao: `TARJA ~ "vermelha" && grupoEquivalenciaId != null`,
+			expected:  "filtro inválido na posição 23: o campo 'grupoEquivalenciaId' é atribuído após o filtro e não pode ser filtrado no processamento",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The expression matches medicines of the processed output, so
+			// only the moment the filter runs makes it unusable there.
+			filtro, err := parseFiltro(tc.expressao)
+			if err != nil {
+				t.Fatalf("parseFiltro failed: %v", err)
+			}
+			if len(consultaMedicamentos(output.Medicamentos, Consulta{Filtro: filtro})) == 0 {
+				t.Errorf("esperado: medicamentos aceitos pelo filtro, obtido: nenhum")
+			}
+
+			_, err = parseFiltroProcessamento(tc.expressao)
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("esperado: %q, obtido: %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const historicoPadrao = "cmed-historico.json.gz"
+
+// Historico stores the prices of every ingested release, indexed by GGREM
+// code, price column and release date (Metadados.Data).
+type Historico struct {
+	Versoes []string                                 `json:"versoes"`
+	Precos  map[string]map[string]map[string]float64 `json:"precos"`
+}
+
+type PontoHistorico struct {
+	Data               string   `json:"data"`
+	Valor              float64  `json:"valor"`
+	Variacao           *float64 `json:"variacao,omitempty"`
+	VariacaoPercentual *float64 `json:"variacaoPercentual,omitempty"`
+}
+
+func novoHistorico() *Historico {
+	return &Historico{
+		Versoes: []string{},
+		Precos:  make(map[string]map[string]map[string]float64),
+	}
+}
+
+func carregaHistorico(path string) (*Historico, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return novoHistorico(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer gz.Close()
+
+	historico := novoHistorico()
+	if err := json.NewDecoder(gz).Decode(historico); err != nil {
+		return nil, fmt.Errorf("failed to decode history file: %w", err)
+	}
+	return historico, nil
+}
+
+// salva writes the history to a temporary file first, so an interrupted
+// write never corrupts the existing store.
+func (h *Historico) salva(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create history file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(gz).Encode(h); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compress history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close history file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// adiciona stores the prices of a release, replacing the prices previously
+// ingested for the same date. It returns the number of prices stored.
+func (h *Historico) adiciona(output Output) (int, error) {
+	data := output.Metadados.Data
+	if data == "" {
+		return 0, fmt.Errorf("a versão não possui data nos metadados")
+	}
+	// A zero date comes from a spreadsheet processed without --data.
+	if dataTime, err := time.Parse("2006-01-02", data); err != nil || dataTime.IsZero() {
+		return 0, fmt.Errorf("data inválida nos metadados: %s", data)
+	}
+
+	if i, found := slices.BinarySearch(h.Versoes, data); found {
+		for _, colunas := range h.Precos {
+			for _, serie := range colunas {
+				delete(serie, data)
+			}
+		}
+	} else {
+		h.Versoes = slices.Insert(h.Versoes, i, data)
+	}
+
+	total := 0
+	for _, medicamento := range output.Medicamentos {
+		ggrem, ok := medicamento[CodigoGGREM].(string)
+		if !ok {
+			continue
+		}
+		for _, header := range cabecalho {
+			valor, ok := medicamento[header].(float64)
+			if !ok || !ehColunaPreco(header) {
+				continue
+			}
+			if h.Precos[ggrem] == nil {
+				h.Precos[ggrem] = make(map[string]map[string]float64)
+			}
+			if h.Precos[ggrem][header] == nil {
+				h.Precos[ggrem][header] = make(map[string]float64)
+			}
+			h.Precos[ggrem][header][data] = valor
+			total++
+		}
+	}
+	return total, nil
+}
+
+// serie returns the time series of each price column of a GGREM code, in
+// date order, with the change from the previous release.
+func (h *Historico) serie(ggrem string) map[string][]PontoHistorico {
+	series := make(map[string][]PontoHistorico)
+	for header, valores := range h.Precos[ggrem] {
+		datas := make([]string, 0, len(valores))
+		for data := range valores {
+			datas = append(datas, data)
+		}
+		sort.Strings(datas)
+
+		pontos := make([]PontoHistorico, 0, len(datas))
+		for i, data := range datas {
+			ponto := PontoHistorico{Data: data, Valor: valores[data]}
+			if i > 0 {
+				anterior := valores[datas[i-1]]
+				variacao := ponto.Valor - anterior
+				ponto.Variacao = &variacao
+				if anterior != 0 {
+					percentual := variacao / anterior * 100
+					ponto.VariacaoPercentual = &percentual
+				}
+			}
+			pontos = append(pontos, ponto)
+		}
+		series[header] = pontos
+	}
+	return series
+}
+
+// validaArquivosIngest checks that the date of every spreadsheet is known:
+// the --data flag dates a single .xlsx file, since every version added to
+// the history must have its own date.
+func validaArquivosIngest(paths []string, data string) error {
+	var planilhas []string
+	for _, path := range paths {
+		if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+			planilhas = append(planilhas, path)
+		}
+	}
+	switch {
+	case len(planilhas) > 0 && data == "":
+		return fmt.Errorf("Informe a data da planilha %s com a flag --data", planilhas[0])
+	case len(planilhas) > 1:
+		return fmt.Errorf("A flag --data se aplica a uma única planilha; adicione %s em comandos separados", strings.Join(planilhas, ", "))
+	}
+	return nil
+}
+
+func runIngest(args []string) {
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	db := flags.String("db", historicoPadrao, "Arquivo do histórico de preços")
+	data := flags.String("data", "", "Data da planilha no formato AAAA-MM-DD (obrigatória para arquivos .xlsx)")
+	dataAtualizacao := flags.String("data-atualizacao", "", "Data de atualização da planilha no formato AAAA-MM-DD")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		log.Fatal("Uso: cmed-parser ingest [flags] <arquivo.xlsx|json|zip>...")
+	}
+
+	var dataTime, dataAtualizacaoTime time.Time
+	if *data != "" {
+		var err error
+		if dataTime, dataAtualizacaoTime, err = parseDatas(*data, *dataAtualizacao); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := validaArquivosIngest(flags.Args(), *data); err != nil {
+		log.Fatal(err)
+	}
+
+	historico, err := carregaHistorico(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, path := range flags.Args() {
+		output, err := carregaOutput(path, dataTime, dataAtualizacaoTime)
+		if err != nil {
+			log.Fatal(err)
+		}
+		total, err := historico.adiciona(output)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		fmt.Printf("Versão %s de %s adicionada ao histórico (%d preços)\n", output.Metadados.Data, path, total)
+	}
+
+	if err := historico.salva(*db); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runHistorico(args []string) {
+	flags := flag.NewFlagSet("historico", flag.ExitOnError)
+	db := flags.String("db", historicoPadrao, "Arquivo do histórico de preços")
+	ggrem := flags.String("ggrem", "", "Código GGREM do medicamento")
+	coluna := flags.String("coluna", "", "Coluna de preço (por exemplo, \"PF 18%\"); se omitida, exibe todas")
+	flags.Parse(args)
+
+	if *ggrem == "" {
+		log.Fatal("Uso: cmed-parser historico --ggrem <código> [flags]")
+	}
+
+	historico, err := carregaHistorico(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	series := historico.serie(*ggrem)
+	if len(series) == 0 {
+		log.Fatalf("Código GGREM %s não encontrado no histórico", *ggrem)
+	}
+	if *coluna != "" {
+		serie, ok := series[*coluna]
+		if !ok {
+			log.Fatalf("Coluna %s não encontrada no histórico do código GGREM %s", *coluna, *ggrem)
+		}
+		series = map[string][]PontoHistorico{*coluna: serie}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(series); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func versaoHistorico(data string, pf18, pmvg18 any) Output {
+	return Output{
+		Metadados: Metadados{Data: data},
+		Medicamentos: []Medicamento{
+			{CodigoGGREM: "500101101111417", PF18: pf18, PMVG18: pmvg18, CAP: true},
+			{CodigoGGREM: nil, PF18: 1.0},
+		},
+	}
+}
+
+func TestHistorico(t *testing.T) {
+	historico := novoHistorico()
+
+	for _, versao := range []Output{
+		versaoHistorico("2025-04-01", 110.0, 88.0),
+		versaoHistorico("2024-04-01", 100.0, 80.0),
+		versaoHistorico("2025-04-01", 105.0, nil),
+	} {
+		if _, err := historico.adiciona(versao); err != nil {
+			t.Fatalf("adiciona failed: %v", err)
+		}
+	}
+
+	if expected := []string{"2024-04-01", "2025-04-01"}; !reflect.DeepEqual(historico.Versoes, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, historico.Versoes)
+	}
+
+	variacao, percentual := 5.0, 5.0
+	expected := map[string][]PontoHistorico{
+		PF18: {
+			{Data: "2024-04-01", Valor: 100},
+			{Data: "2025-04-01", Valor: 105, Variacao: &variacao, VariacaoPercentual: &percentual},
+		},
+		PMVG18: {
+			{Data: "2024-04-01", Valor: 80},
+		},
+	}
+	if series := historico.serie("500101101111417"); !reflect.DeepEqual(series, expected) {
+		t.Errorf("esperado: %+v, obtido: %+v", expected, series)
+	}
+
+	if _, err := historico.adiciona(Output{}); err == nil {
+		t.Error("esperado erro para versão sem data")
+	}
+	if _, err := historico.adiciona(versaoHistorico("0001-01-01", 1.0, 1.0)); err == nil {
+		t.Error("esperado erro para versão com data zero")
+	}
+}
+
+func TestSalvaCarregaHistorico(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "historico.json.gz")
+
+	vazio, err := carregaHistorico(path)
+	if err != nil {
+		t.Fatalf("carregaHistorico failed: %v", err)
+	}
+	if len(vazio.Versoes) != 0 {
+		t.Errorf("esperado histórico vazio, obtido: %v", vazio.Versoes)
+	}
+
+	historico := novoHistorico()
+	if _, err := historico.adiciona(versaoHistorico("2024-04-01", 100.0, 80.0)); err != nil {
+		t.Fatalf("adiciona failed: %v", err)
+	}
+	if err := historico.salva(path); err != nil {
+		t.Fatalf("salva failed: %v", err)
+	}
+
+	carregado, err := carregaHistorico(path)
+	if err != nil {
+		t.Fatalf("carregaHistorico failed: %v", err)
+	}
+	if !reflect.DeepEqual(carregado, historico) {
+		t.Errorf("esperado: %+v, obtido: %+v", historico, carregado)
+	}
+}
+
+func TestValidaArquivosIngest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		paths    []string
+		data     string
+		expected bool
+	}{
+		{"saídas do parser", []string{"a.json", "b.zip"}, "", true},
+		{"planilha com data", []string{"a.xlsx", "b.json"}, "2024-01-01", true},
+		{"planilha sem data", []string{"a.xlsx"}, "", false},
+		{"planilhas com a mesma data", []string{"a.xlsx", "b.XLSX"}, "2024-01-01", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validaArquivosIngest(tc.paths, tc.data)
+			if result := err == nil; result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v (%v)", tc.expected, result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package impostos computes CMED factory prices (PF) and maximum government
+// sale prices (PMVG) with taxes from the price without taxes, by grossing up
+// the ICMS rate and the PIS/COFINS rate of the product's credit list.
+package impostos
+
+import "math"
+
+// Lista is the PIS/COFINS credit concession list of a product.
+type Lista string
+
+const (
+	Positiva Lista = "POSITIVA"
+	Negativa Lista = "NEGATIVA"
+	Neutra   Lista = "NEUTRA"
+)
+
+// Aliquotas holds the PIS/COFINS rate of each list, as a fraction.
+type Aliquotas map[Lista]float64
+
+// AliquotasPadrao are the PIS/COFINS rates used by default. Products of the
+// positive list have the contributions offset by a presumed credit, the
+// negative list bears the single-phase rate (2.1% + 9.9%) and the neutral
+// list the non-cumulative regime rate (1.65% + 7.6%).
+var AliquotasPadrao = Aliquotas{
+	Positiva: 0,
+	Negativa: 0.12,
+	Neutra:   0.0925,
+}
+
+// PISCOFINS returns the PIS/COFINS rate of a product. Prices for free trade
+// areas (ALC) are exempt from PIS/COFINS. The boolean is false when the list
+// has no rate.
+func (a Aliquotas) PISCOFINS(lista Lista, alc bool) (float64, bool) {
+	aliquota, ok := a[lista]
+	if !ok {
+		return 0, false
+	}
+	if alc {
+		return 0, true
+	}
+	return aliquota, true
+}
+
+// PrecoComImpostos grosses up a price without taxes by the ICMS and
+// PIS/COFINS rates, both as fractions, and rounds it to cents. Taxes are
+// calculated "por dentro", that is, included in their own base:
+//
+//	preço = base / (1 - icms - pisCofins)
+func PrecoComImpostos(base, icms, pisCofins float64) float64 {
+	return math.Round(base/(1-icms-pisCofins)*100) / 100
+}
+
+// Preco returns the price with taxes of a product of the given list for an
+// ICMS rate, as a fraction. The boolean is false when the list has no rate.
+func (a Aliquotas) Preco(base, icms float64, lista Lista, alc bool) (float64, bool) {
+	pisCofins, ok := a.PISCOFINS(lista, alc)
+	if !ok {
+		return 0, false
+	}
+	return PrecoComImpostos(base, icms, pisCofins), true
+}
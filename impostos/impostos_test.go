@@ -0,0 +1,55 @@
+package impostos
+
+import "testing"
+
+func TestPrecoComImpostos(t *testing.T) {
+	testCases := []struct {
+		name      string
+		base      float64
+		icms      float64
+		pisCofins float64
+		expected  float64
+	}{
+		{"sem impostos", 100, 0, 0, 100},
+		{"somente ICMS", 82, 0.18, 0, 100},
+		{"ICMS e PIS/COFINS", 70, 0.18, 0.12, 100},
+		{"arredondamento", 10, 0.175, 0.0925, 13.65},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := PrecoComImpostos(tc.base, tc.icms, tc.pisCofins)
+			if result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAliquotasPreco(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lista    Lista
+		alc      bool
+		expected float64
+		ok       bool
+	}{
+		{"lista positiva", Positiva, false, 121.95, true},
+		{"lista negativa", Negativa, false, 142.86, true},
+		{"lista neutra", Neutra, false, 137.46, true},
+		{"lista negativa ALC", Negativa, true, 121.95, true},
+		{"lista desconhecida", Lista("MISTA"), false, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := AliquotasPadrao.Preco(100, 0.18, tc.lista, tc.alc)
+			if ok != tc.ok {
+				t.Fatalf("esperado ok: %v, obtido: %v", tc.ok, ok)
+			}
+			if result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type CadastroLaboratorio struct {
+	Nome      string   `json:"nome" xml:"Nome"`
+	Variantes []string `json:"variantes" xml:"Variantes>Variante"`
+	Produtos  int      `json:"produtos" xml:"Produtos"`
+}
+
+// raizCNPJ returns the first eight digits of a CNPJ, formatted as
+// "00.000.000", which identify the company regardless of the branch
+// (matriz/filial). CNPJs with fewer digits are returned unchanged.
+func raizCNPJ(cnpj string) string {
+	digitos := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, cnpj)
+	if len(digitos) < 8 {
+		return cnpj
+	}
+	return fmt.Sprintf("%s.%s.%s", digitos[0:2], digitos[2:5], digitos[5:8])
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestRaizCNPJ(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"matriz", "12.345.678/0001-90", "12.345.678"},
+		{"filial", "12.345.678/0002-71", "12.345.678"},
+		{"sem formatação", "12345678000190", "12.345.678"},
+		{"inválido", "123", "123"},
+		{"string vazia", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := raizCNPJ(tc.input)
+			if result != tc.expected {
+				t.Errorf("esperado: %s, obtido: %s", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	dataNomeISORegex     = regexp.MustCompile(`(\d{4})[-_.]?(\d{2})[-_.]?(\d{2})`)
+	dataNomeBrasilRegex  = regexp.MustCompile(`(\d{2})[-_.](\d{2})[-_.](\d{4})`)
+	dataObservacoesRegex = regexp.MustCompile(`(\d{2})/(\d{2})/(\d{4})`)
+	caracteresGlob       = "*?["
+)
+
+type ResultadoLote struct {
+	Arquivo      string `json:"arquivo"`
+	Data         string `json:"data,omitempty"`
+	Medicamentos int    `json:"medicamentos"`
+	Avisos       int    `json:"avisos"`
+	Erro         string `json:"erro,omitempty"`
+}
+
+type ResumoLote struct {
+	Sucessos int             `json:"sucessos"`
+	Falhas   int             `json:"falhas"`
+	Arquivos []ResultadoLote `json:"arquivos"`
+}
+
+func dataValida(ano, mes, dia string) (time.Time, bool) {
+	data, err := time.Parse("2006-01-02", ano+"-"+mes+"-"+dia)
+	if err != nil || data.Year() < 2000 {
+		return time.Time{}, false
+	}
+	return data, true
+}
+
+// inferDataNome looks for a date in a file name, either as AAAA-MM-DD
+// (with any of "-", "_" or "." as separator, or none) or as DD-MM-AAAA.
+func inferDataNome(nome string) (time.Time, bool) {
+	nome = strings.TrimSuffix(filepath.Base(nome), filepath.Ext(nome))
+	for _, m := range dataNomeISORegex.FindAllStringSubmatch(nome, -1) {
+		if data, ok := dataValida(m[1], m[2], m[3]); ok {
+			return data, true
+		}
+	}
+	for _, m := range dataNomeBrasilRegex.FindAllStringSubmatch(nome, -1) {
+		if data, ok := dataValida(m[3], m[2], m[1]); ok {
+			return data, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// inferDataObservacoes returns the first DD/MM/AAAA date found in the notes
+// above the spreadsheet header, such as the publication date.
+func inferDataObservacoes(observacoes []string) (time.Time, bool) {
+	for _, observacao := range observacoes {
+		for _, m := range dataObservacoesRegex.FindAllStringSubmatch(observacao, -1) {
+			if data, ok := dataValida(m[3], m[2], m[1]); ok {
+				return data, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// expandeArquivos resolves the arguments of the batch mode into a sorted
+// list of .xlsx files. Arguments may be files, glob patterns or
+// directories, which are searched recursively.
+func expandeArquivos(args []string) ([]string, error) {
+	vistos := make(map[string]bool)
+	var arquivos []string
+	adiciona := func(path string) {
+		if !vistos[path] {
+			vistos[path] = true
+			arquivos = append(arquivos, path)
+		}
+	}
+
+	for _, arg := range args {
+		if strings.ContainsAny(arg, caracteresGlob) {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("padrão inválido %s: %w", arg, err)
+			}
+			for _, match := range matches {
+				if strings.EqualFold(filepath.Ext(match), ".xlsx") {
+					adiciona(match)
+				}
+			}
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			adiciona(arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".xlsx") {
+				adiciona(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Sort(arquivos)
+	return arquivos, nil
+}
+
+// processaArquivoLote processes one file of the batch, taking its date from
+// the file name or, failing that, from the spreadsheet notes.
+func processaArquivoLote(path string, processamento OpcoesProcessamento, saida OpcoesSaida) ResultadoLote {
+	resultado := ResultadoLote{Arquivo: path}
+
+	output, err := processExcelFile(path, time.Time{}, time.Time{}, processamento)
+	if err != nil {
+		resultado.Erro = err.Error()
+		return resultado
+	}
+
+	data, ok := inferDataNome(path)
+	if !ok {
+		data, ok = inferDataObservacoes(output.Metadados.Observacoes)
+	}
+	if !ok {
+		resultado.Erro = "não foi possível identificar a data da planilha pelo nome do arquivo nem pelas observações"
+		return resultado
+	}
+	output.Metadados.Data = data.Format("2006-01-02")
+	output.Metadados.DataAtualizacao = output.Metadados.Data
+
+	if err := escreveSaida(output, path, saida); err != nil {
+		resultado.Erro = err.Error()
+		return resultado
+	}
+
+	resultado.Data = output.Metadados.Data
+	resultado.Medicamentos = len(output.Medicamentos)
+	resultado.Avisos = len(output.Avisos)
+	return resultado
+}
+
+// workersLinhasLote returns the number of row workers of each file when
+// workers files are processed at once. Unless set explicitly, the files
+// share the CPUs, so that the batch does not start a full row pipeline per
+// file.
+func workersLinhasLote(workers, workersLinhas int) int {
+	if workersLinhas > 0 {
+		return workersLinhas
+	}
+	return max(1, runtime.NumCPU()/max(workers, 1))
+}
+
+// processaLote processes the files with a pool of workers. The results keep
+// the order of the files.
+func processaLote(arquivos []string, workers int, processamento OpcoesProcessamento, saida OpcoesSaida) ResumoLote {
+	resultados := make([]ResultadoLote, len(arquivos))
+	indices := make(chan int)
+
+	workers = max(workers, 1)
+	processamento.Workers = workersLinhasLote(workers, processamento.Workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				resultados[i] = processaArquivoLote(arquivos[i], processamento, saida)
+			}
+		}()
+	}
+	for i := range arquivos {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	resumo := ResumoLote{Arquivos: resultados}
+	for _, resultado := range resultados {
+		if resultado.Erro == "" {
+			resumo.Sucessos++
+		} else {
+			resumo.Falhas++
+		}
+	}
+	return resumo
+}
+
+func runLote(args []string) {
+	flags := flag.NewFlagSet("lote", flag.ExitOnError)
+	saida := registraFlagsSaida(flags)
+	workers := flags.Int("workers", runtime.NumCPU(), "Número de arquivos processados simultaneamente")
+	workersLinhas := flags.Int("row-workers", 0, "Número de goroutines que processam as linhas de cada arquivo (padrão: número de CPUs dividido por --workers)")
+	resumoPath := flags.String("resumo", "resumo-lote.json", "Arquivo do resumo do processamento")
+	filtro := flags.String("filter", "", "Expressão que seleciona os medicamentos incluídos nas saídas")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		log.Fatal("Uso: cmed-parser lote [flags] <arquivo.xlsx|diretório|padrão>...")
+	}
+
+	opcoesSaida, err := saida.opcoes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opcoesProcessamento := OpcoesProcessamento{Workers: *workersLinhas}
+	if *filtro != "" {
+		if opcoesProcessamento.Filtro, err = parseFiltroProcessamento(*filtro); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	arquivos, err := expandeArquivos(flags.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(arquivos) == 0 {
+		log.Fatal("Nenhum arquivo .xlsx encontrado")
+	}
+
+	resumo := processaLote(arquivos, *workers, opcoesProcessamento, opcoesSaida)
+
+	resumoFile, err := os.Create(*resumoPath)
+	if err != nil {
+		log.Fatalf("failed to create summary file: %v", err)
+	}
+	defer resumoFile.Close()
+
+	encoder := json.NewEncoder(resumoFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(resumo); err != nil {
+		log.Fatalf("failed to encode summary: %v", err)
+	}
+
+	for _, resultado := range resumo.Arquivos {
+		if resultado.Erro != "" {
+			log.Printf("Falha em %s: %s", resultado.Arquivo, resultado.Erro)
+		}
+	}
+	fmt.Printf("%d arquivos processados com sucesso e %d falhas. Resumo em %s\n", resumo.Sucessos, resumo.Falhas, *resumoPath)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestInferDataNome(t *testing.T) {
+	testCases := []struct {
+		name     string
+		arquivo  string
+		expected string
+	}{
+		{"AAAAMMDD", "xls_conformidade_site_20240715_112233.xlsx", "2024-07-15"},
+		{"AAAA-MM-DD", "/arquivo/cmed-2023-03-31.xlsx", "2023-03-31"},
+		{"DD.MM.AAAA", "lista_15.07.2024.xlsx", "2024-07-15"},
+		{"data inválida ignorada", "lista_99999999_01-02-2022.xlsx", "2022-02-01"},
+		{"sem data", "lista.xlsx", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, ok := inferDataNome(tc.arquivo)
+			result := ""
+			if ok {
+				result = data.Format("2006-01-02")
+			}
+			if result != tc.expected {
+				t.Errorf("esperado: %q, obtido: %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestInferDataObservacoes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		observacoes []string
+		expected    string
+	}{
+		{"primeira data válida", []string{"Lista de preços", "Publicada em 31/13/2024, atualizada em 10/06/2024"}, "2024-06-10"},
+		{"sem data", []string{"Sem data"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, ok := inferDataObservacoes(tc.observacoes)
+			result := ""
+			if ok {
+				result = data.Format("2006-01-02")
+			}
+			if result != tc.expected {
+				t.Errorf("esperado: %q, obtido: %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestWorkersLinhasLote(t *testing.T) {
+	cpus := runtime.NumCPU()
+	testCases := []struct {
+		name          string
+		workers       int
+		workersLinhas int
+		expected      int
+	}{
+		{"um arquivo", 1, 0, cpus},
+		{"CPUs divididas", 2, 0, max(1, cpus/2)},
+		{"mais arquivos que CPUs", cpus + 1, 0, 1},
+		{"workers inválidos", 0, 0, cpus},
+		{"explícito", cpus, 3, 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := workersLinhasLote(tc.workers, tc.workersLinhas); result != tc.expected {
+				t.Errorf("esperado: %d, obtido: %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+// salvaPlanilhaLote writes a minimal CMED spreadsheet with one medicine.
+func salvaPlanilhaLote(t *testing.T, path, observacao string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	f.SetCellValue(sheet, "A1", observacao)
+	f.SetSheetRow(sheet, "A2", &cabecalho)
+	f.SetSheetRow(sheet, "A3", &[]string{"IBUPROFENO", "12.345.678/0001-90", "LAB A"})
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("failed to save spreadsheet: %v", err)
+	}
+}
+
+func TestExpandeArquivos(t *testing.T) {
+	tempDir := t.TempDir()
+	subDir := filepath.Join(tempDir, "2024")
+	if err := os.Mkdir(subDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, nome := range []string{"a.xlsx", "b.xlsx", "notas.txt", filepath.Join("2024", "c.xlsx")} {
+		if err := os.WriteFile(filepath.Join(tempDir, nome), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	arquivos, err := expandeArquivos([]string{filepath.Join(tempDir, "*.xlsx"), subDir, filepath.Join(tempDir, "a.xlsx")})
+	if err != nil {
+		t.Fatalf("expandeArquivos failed: %v", err)
+	}
+	expected := []string{
+		filepath.Join(tempDir, "2024", "c.xlsx"),
+		filepath.Join(tempDir, "a.xlsx"),
+		filepath.Join(tempDir, "b.xlsx"),
+	}
+	if !reflect.DeepEqual(arquivos, expected) {
+		t.Errorf("esperado: %v, obtido: %v", expected, arquivos)
+	}
+
+	if _, err := expandeArquivos([]string{filepath.Join(tempDir, "inexistente.xlsx")}); err == nil {
+		t.Error("esperado erro para arquivo inexistente")
+	}
+}
+
+func TestProcessaLote(t *testing.T) {
+	tempDir := t.TempDir()
+	porNome := filepath.Join(tempDir, "cmed_20240715.xlsx")
+	porObservacao := filepath.Join(tempDir, "cmed.xlsx")
+	semData := filepath.Join(tempDir, "sem-data.xlsx")
+	invalido := filepath.Join(tempDir, "invalido.xlsx")
+
+	salvaPlanilhaLote(t, porNome, "Lista de preços")
+	salvaPlanilhaLote(t, porObservacao, "Publicada em 03/06/2024")
+	salvaPlanilhaLote(t, semData, "Lista de preços")
+	if err := os.WriteFile(invalido, []byte("não é xlsx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumo := processaLote([]string{porNome, porObservacao, semData, invalido}, 2, OpcoesProcessamento{}, OpcoesSaida{Canonico: true})
+	if resumo.Sucessos != 2 || resumo.Falhas != 2 {
+		t.Fatalf("esperado 2 sucessos e 2 falhas, obtido %d e %d: %+v", resumo.Sucessos, resumo.Falhas, resumo.Arquivos)
+	}
+
+	testCases := []struct {
+		name  string
+		data  string
+		falha bool
+	}{
+		{"data pelo nome", "2024-07-15", false},
+		{"data pelas observações", "2024-06-03", false},
+		{"sem data", "", true},
+		{"planilha inválida", "", true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resultado := resumo.Arquivos[i]
+			if tc.falha {
+				if resultado.Erro == "" {
+					t.Errorf("esperado erro para %s", resultado.Arquivo)
+				}
+				return
+			}
+			if resultado.Erro != "" || resultado.Data != tc.data || resultado.Medicamentos != 1 {
+				t.Errorf("esperado: data %s e 1 medicamento, obtido: %+v", tc.data, resultado)
+			}
+		})
+	}
+
+	output, err := carregaOutput(porNome[:len(porNome)-len(".xlsx")]+".json", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("carregaOutput failed: %v", err)
+	}
+	if output.Metadados.Data != "2024-07-15" || output.Metadados.GeradoEm != "" {
+		t.Errorf("esperado: data 2024-07-15 sem gerado-em, obtido: %+v", output.Metadados)
+	}
+}
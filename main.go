@@ -1,16 +1,16 @@
 package main
 
 import (
-	"archive/zip"
 	"bytes"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
-	"slices"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -98,6 +98,43 @@ const (
 	Tarja                           = "TARJA"
 )
 
+// Derived fields, computed from the spreadsheet columns.
+const (
+	Substancias             = "substancias"
+	Classificacao           = "classificacaoTerapeutica"
+	TipoProdutoCodigo       = "tipoProduto"
+	RegimePrecoCodigo       = "regimePreco"
+	TarjaCodigo             = "tarja"
+	ListaPISCOFINSCodigo    = "listaPisCofins"
+	PrecosMarcados          = "precosMarcados"
+	ApresentacaoID          = "apresentacaoId"
+	ApresentacaoDetalhes    = "apresentacaoDetalhes"
+	GrupoEquivalenciaID     = "grupoEquivalenciaId"
+	PrecosUnitarios         = "precosUnitarios"
+	QuantidadeIndeterminada = "quantidadeIndeterminada"
+	Linha                   = "linha"
+)
+
+var camposDerivados = []string{
+	Substancias,
+	Classificacao,
+	TipoProdutoCodigo,
+	RegimePrecoCodigo,
+	TarjaCodigo,
+	ListaPISCOFINSCodigo,
+	PrecosMarcados,
+	ApresentacaoID,
+	ApresentacaoDetalhes,
+	GrupoEquivalenciaID,
+	PrecosUnitarios,
+	QuantidadeIndeterminada,
+	Linha,
+}
+
+// versao is the parser version, set at build time with
+// -ldflags "-X main.versao=...".
+var versao = "dev"
+
 var cabecalho = []string{
 	PrincipioAtivo,
 	CNPJ,
@@ -175,18 +212,49 @@ var cabecalho = []string{
 }
 
 type Metadados struct {
-	Data            string   `json:"data"`
-	DataAtualizacao string   `json:"data-atualizacao,omitempty"`
-	Observacoes     []string `json:"observacoes"`
+	Data            string   `json:"data" xml:"Data"`
+	DataAtualizacao string   `json:"data-atualizacao,omitempty" xml:"DataAtualizacao,omitempty"`
+	Observacoes     []string `json:"observacoes" xml:"Observacoes>Observacao"`
+	VersaoSchema    string   `json:"versao-schema,omitempty" xml:"VersaoSchema,omitempty"`
+	VersaoParser    string   `json:"versao-parser,omitempty" xml:"VersaoParser,omitempty"`
+	Arquivo         string   `json:"arquivo,omitempty" xml:"Arquivo,omitempty"`
+	SHA256          string   `json:"sha256,omitempty" xml:"SHA256,omitempty"`
+	Planilha        string   `json:"planilha,omitempty" xml:"Planilha,omitempty"`
+	LinhaCabecalho  int      `json:"linha-cabecalho,omitempty" xml:"LinhaCabecalho,omitempty"`
+	Linhas          int      `json:"linhas,omitempty" xml:"Linhas,omitempty"`
+	GeradoEm        string   `json:"gerado-em,omitempty" xml:"GeradoEm,omitempty"`
+	Canonico        bool     `json:"canonico,omitempty" xml:"Canonico,omitempty"`
+	Colunas         []string `json:"colunas,omitempty" xml:"Colunas>Coluna,omitempty"`
+	OmiteNulos      bool     `json:"omite-nulos,omitempty" xml:"OmiteNulos,omitempty"`
+	Filtro          string   `json:"filtro,omitempty" xml:"Filtro,omitempty"`
 }
 
 type Medicamento map[string]interface{}
 
+type ApresentacaoCatalogo struct {
+	ID           int      `json:"id" xml:"id,attr"`
+	Descricao    string   `json:"descricao" xml:"Descricao"`
+	Ocorrencias  int      `json:"ocorrencias" xml:"Ocorrencias"`
+	Variantes    []string `json:"variantes" xml:"Variantes>Variante"`
+	CodigosGGREM []string `json:"codigosGgrem" xml:"CodigosGGREM>CodigoGGREM"`
+}
+
+type ResumoTributario struct {
+	PorLista       map[ListaPISCOFINS]int            `json:"porLista"`
+	PorLaboratorio map[string]map[ListaPISCOFINS]int `json:"porLaboratorio"`
+}
+
 type Output struct {
-	Metadados     Metadados         `json:"metadados"`
-	Medicamentos  []Medicamento     `json:"medicamentos"`
-	Laboratorios  map[string]string `json:"laboratorios"`
-	Apresentacoes []string          `json:"apresentacoes"`
+	Metadados           Metadados                      `json:"metadados"`
+	Medicamentos        []Medicamento                  `json:"medicamentos"`
+	Laboratorios        map[string]CadastroLaboratorio `json:"laboratorios"`
+	GruposLaboratorios  map[string][]string            `json:"gruposLaboratorios"`
+	Apresentacoes       []ApresentacaoCatalogo         `json:"apresentacoes"`
+	Substancias         map[string][]string            `json:"substancias"`
+	ClassesTerapeuticas map[string]string              `json:"classesTerapeuticas"`
+	ResumoTributario    ResumoTributario               `json:"resumoTributario"`
+	GruposEquivalencia  []GrupoEquivalencia            `json:"gruposEquivalencia"`
+	Avisos              []string                       `json:"avisos,omitempty"`
 }
 
 func convertIntToExcelColumn(index int) string {
@@ -200,121 +268,185 @@ func convertIntToExcelColumn(index int) string {
 }
 
 func writeJSONFile(output Output, infilePath string) error {
-	outfilePath := strings.TrimSuffix(infilePath, filepath.Ext(infilePath)) + ".json"
-	jsonFile, err := os.Create(outfilePath)
-	if err != nil {
-		return fmt.Errorf("failed to create json file: %w", err)
-	}
-	defer jsonFile.Close()
-
-	encoder := json.NewEncoder(jsonFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(output); err != nil {
-		return fmt.Errorf("failed to encode json: %w", err)
-	}
-
-	fmt.Printf("Arquivo %s criado!\n", outfilePath)
-	return nil
+	return writeSaidaFile(output, infilePath, formatosSaida[FormatoJSON], OpcoesSaida{})
 }
 
 func writeZipFile(output Output, infilePath string) error {
-	outfilePath := strings.TrimSuffix(infilePath, filepath.Ext(infilePath)) + ".zip"
+	return writeSaidaZip(output, infilePath, formatosSaida[FormatoJSON], OpcoesSaida{})
+}
 
-	// Create a buffer to write our archive to.
-	buf := new(bytes.Buffer)
+// OpcoesSaida configures how an output is written.
+type OpcoesSaida struct {
+	Zip      bool
+	Canonico bool
+	Projecao Projecao
+	// Formato is a key of formatosSaida; empty means JSON.
+	Formato string
+	// PrecosFHIR adds a ChargeItemDefinition with the prices of each
+	// medicine to the FHIR bundle.
+	PrecosFHIR bool
+	// Indice also writes the full-text search index next to the output.
+	Indice bool
+}
 
-	// Create a new zip archive.
-	zipWriter := zip.NewWriter(buf)
+// flagsSaida holds the flags shared by the commands that write outputs.
+type flagsSaida struct {
+	zip         *bool
+	canonico    *bool
+	colunas     *string
+	excluidas   *string
+	omitirNulos *bool
+	indice      *bool
+	formato     *string
+	precosFHIR  *bool
+}
 
-	// Create a new file in the zip archive.
-	zipFile, err := zipWriter.Create(strings.TrimSuffix(filepath.Base(infilePath), filepath.Ext(infilePath)) + ".json")
-	if err != nil {
-		return fmt.Errorf("failed to create zip file: %w", err)
+func registraFlagsSaida(flags *flag.FlagSet) flagsSaida {
+	return flagsSaida{
+		zip:         flags.Bool("zip", false, "Gerar arquivo zipado ao invés de JSON"),
+		canonico:    flags.Bool("canonico", false, "Gerar saída em ordem canônica, para arquivos reproduzíveis"),
+		colunas:     flags.String("columns", "", "Colunas incluídas nos medicamentos, separadas por vírgula (nome da constante ou texto do cabeçalho)"),
+		excluidas:   flags.String("exclude-columns", "", "Colunas removidas dos medicamentos, separadas por vírgula"),
+		omitirNulos: flags.Bool("omit-null", false, "Omitir os campos nulos dos medicamentos"),
+		indice:      flags.Bool("indice", false, "Gerar também o índice de busca (.indice.json.gz)"),
+		formato:     flags.String("format", FormatoJSON, "Formato da saída: json, fhir ou xml"),
+		precosFHIR:  flags.Bool("fhir-prices", false, "Incluir os preços de PF e PMVG como ChargeItemDefinition no formato fhir"),
 	}
+}
 
-	// Write the JSON data to the file in the zip archive.
-	encoder := json.NewEncoder(zipFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(output); err != nil {
-		return fmt.Errorf("failed to encode json: %w", err)
+func (f flagsSaida) opcoes() (OpcoesSaida, error) {
+	projecao, err := novaProjecao(*f.colunas, *f.excluidas, *f.omitirNulos)
+	if err != nil {
+		return OpcoesSaida{}, err
+	}
+	if _, ok := formatosSaida[*f.formato]; !ok {
+		return OpcoesSaida{}, fmt.Errorf("formato de saída desconhecido: %s. Use %s", *f.formato, strings.Join(nomesFormatosSaida(), ", "))
 	}
+	return OpcoesSaida{
+		Zip:        *f.zip,
+		Canonico:   *f.canonico,
+		Projecao:   projecao,
+		Indice:     *f.indice,
+		Formato:    *f.formato,
+		PrecosFHIR: *f.precosFHIR,
+	}, nil
+}
 
-	// Make sure to check the error on Close.
-	if err := zipWriter.Close(); err != nil {
-		return fmt.Errorf("failed to close zip writer: %w", err)
+// escreveSaida writes the output next to the input file in the selected
+// format, optionally zipped. In canonical mode the output is sorted and the
+// generation time is omitted, so it depends only on the input.
+func escreveSaida(output Output, infilePath string, opcoes OpcoesSaida) error {
+	if opcoes.Canonico {
+		ordenaOutput(&output)
+		output.Metadados.Canonico = true
+	} else {
+		output.Metadados.GeradoEm = time.Now().Format(time.RFC3339)
 	}
 
-	// Write the .zip file to disk.
-	if err := os.WriteFile(outfilePath, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to write zip file: %w", err)
+	// The index is built before the projection, which may remove the
+	// indexed columns, and after sorting, since it refers to positions.
+	if opcoes.Indice {
+		if err := writeIndiceBusca(indexaOutput(output), infilePath); err != nil {
+			return err
+		}
 	}
+	opcoes.Projecao.aplica(&output)
 
-	fmt.Printf("Arquivo %s criado!\n", outfilePath)
-	return nil
+	formato := formatosSaida[opcoes.formato()]
+	if opcoes.Zip {
+		return writeSaidaZip(output, infilePath, formato, opcoes)
+	}
+	return writeSaidaFile(output, infilePath, formato, opcoes)
 }
 
-func processExcelFile(infilePath string, data, dataAtualizacao time.Time) (Output, error) {
-	f, err := excelize.OpenFile(infilePath)
+func processExcelFile(infilePath string, data, dataAtualizacao time.Time, opcoes OpcoesProcessamento) (Output, error) {
+	conteudo, err := os.ReadFile(infilePath)
+	if err != nil {
+		return Output{}, fmt.Errorf("failed to read excel file: %w", err)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(conteudo))
 	if err != nil {
 		return Output{}, fmt.Errorf("failed to open excel file: %w", err)
 	}
 
 	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
+	rows, err := f.Rows(sheetName)
 	if err != nil {
 		return Output{}, fmt.Errorf("failed to get rows from sheet: %w", err)
 	}
+	defer rows.Close()
 
-	removeSpaces := regexp.MustCompile(`(\s+)`)
+	output, err := processaPlanilha(linhasExcel(rows), data, dataAtualizacao, opcoes)
+	if err != nil {
+		return Output{}, err
+	}
+
+	hash := sha256.Sum256(conteudo)
+	output.Metadados.Arquivo = filepath.Base(infilePath)
+	output.Metadados.SHA256 = hex.EncodeToString(hash[:])
+	output.Metadados.Planilha = sheetName
+	return output, nil
+}
 
+func processRows(rows [][]string, data, dataAtualizacao time.Time, opcoes OpcoesProcessamento) (Output, error) {
+	return processaPlanilha(linhasSlice(rows), data, dataAtualizacao, opcoes)
+}
+
+// processaPlanilha reads the notes and the header sequentially, then hands
+// the data rows to the concurrent pipeline. Aggregation runs on the
+// calling goroutine, in row order, so the output does not depend on the
+// number of workers.
+func processaPlanilha(proxima fonteLinhas, data, dataAtualizacao time.Time, opcoes OpcoesProcessamento) (Output, error) {
 	var planilhaObservacoes []string
-	laboratoriosList := make(map[string]string)
-	var apresentacaoList []string
 	var medicamentosList []Medicamento
+	agregador := novoAgregador()
+	var avisos []string
+	valoresDesconhecidos := make(map[string]bool)
 	linhaCabecalho := -1
 
-	for i, row := range rows {
-		if linhaCabecalho == -1 {
-			if len(row) > 0 && row[0] == PrincipioAtivo {
-				for j, header := range cabecalho {
-					if !strings.EqualFold(removeSpaces.ReplaceAllString(header, ""), removeSpaces.ReplaceAllString(row[j], "")) {
-						return Output{}, fmt.Errorf("cabeçalho inválido na linha %d e coluna %s: esperado '%s', encontrado '%s'", i+1, convertIntToExcelColumn(j), header, row[j])
-					}
-				}
-				linhaCabecalho = i
-			} else if len(row) > 0 {
-				planilhaObservacoes = append(planilhaObservacoes, row[0])
-			}
-			continue
+	for i := 0; linhaCabecalho == -1; i++ {
+		row, ok, err := proxima()
+		if err != nil {
+			return Output{}, fmt.Errorf("failed to read row %d: %w", i+1, err)
 		}
-
-		medicamento := make(Medicamento)
-		for j, header := range cabecalho {
-			var value any
-			if j < len(row) {
-				value = strings.TrimSpace(row[j])
-			} else {
-				value = ""
-			}
-
-			medicamento[header] = processaValorCelula(value, header)
+		if !ok {
+			break
 		}
-
-		medicamentosList = append(medicamentosList, medicamento)
-
-		if medicamento[CNPJ] != nil {
-			cnpjLaboratorio := medicamento[CNPJ].(string)
-			if _, ok := laboratoriosList[cnpjLaboratorio]; !ok {
-				laboratoriosList[cnpjLaboratorio] = medicamento[Laboratorio].(string)
+		if len(row) > 0 && row[0] == PrincipioAtivo {
+			for j, header := range cabecalho {
+				var valor string
+				if j < len(row) {
+					valor = row[j]
+				}
+				if !strings.EqualFold(espacosRegex.ReplaceAllString(header, ""), espacosRegex.ReplaceAllString(valor, "")) {
+					return Output{}, fmt.Errorf("cabeçalho inválido na linha %d e coluna %s: esperado '%s', encontrado '%s'", i+1, convertIntToExcelColumn(j), header, valor)
+				}
 			}
+			linhaCabecalho = i
+		} else if len(row) > 0 {
+			planilhaObservacoes = append(planilhaObservacoes, row[0])
 		}
+	}
 
-		if medicamento[Apresentacao] != nil {
-			apresentacao := removeAccents(medicamento[Apresentacao].(string))
-			found := slices.Contains(apresentacaoList, apresentacao)
-			if !found {
-				apresentacaoList = append(apresentacaoList, apresentacao)
+	linhas := 0
+	if linhaCabecalho != -1 {
+		var err error
+		linhas, err = processaLinhasConcorrente(proxima, linhaCabecalho+2, opcoes, func(medicamento Medicamento, desconhecidos []valorDesconhecido) {
+			for _, d := range desconhecidos {
+				chave := d.coluna + "\x00" + d.valor.(string)
+				if valoresDesconhecidos[chave] {
+					continue
+				}
+				valoresDesconhecidos[chave] = true
+				avisos = append(avisos, fmt.Sprintf("valor desconhecido na linha %d e coluna %s: '%s'", medicamento[Linha], d.coluna, d.valor))
 			}
+
+			medicamentosList = append(medicamentosList, medicamento)
+			agregador.adiciona(medicamento)
+		})
+		if err != nil {
+			return Output{}, fmt.Errorf("failed to read rows from sheet: %w", err)
 		}
 	}
 
@@ -323,11 +455,16 @@ func processExcelFile(infilePath string, data, dataAtualizacao time.Time) (Outpu
 			Data:            data.Format("2006-01-02"),
 			DataAtualizacao: dataAtualizacao.Format("2006-01-02"),
 			Observacoes:     planilhaObservacoes,
+			VersaoSchema:    versaoSchema,
+			VersaoParser:    versao,
+			LinhaCabecalho:  linhaCabecalho + 1,
+			Linhas:          linhas,
+			Filtro:          opcoes.Filtro.String(),
 		},
-		Medicamentos:  medicamentosList,
-		Laboratorios:  laboratoriosList,
-		Apresentacoes: apresentacaoList,
+		Medicamentos: medicamentosList,
+		Avisos:       avisos,
 	}
+	agregador.preenche(&output)
 
 	return output, nil
 }
@@ -338,10 +475,21 @@ func removeAccents(s string) string {
 	return result
 }
 
-func processaValorCelula(value any, header string) any {
-	valoresRegex := regexp.MustCompile(`^(PF |PMVG )([0-2]|S)`)
-	realRegex := regexp.MustCompile(`^[0-9]+([\.,])[0-9]+\*?$`)
+var (
+	espacosRegex     = regexp.MustCompile(`(\s+)`)
+	colunaPrecoRegex = regexp.MustCompile(`^(PF |PMVG )([0-2]|S)`)
+	valorRealRegex   = regexp.MustCompile(`^[0-9]+([\.,])[0-9]+\*?$`)
+)
 
+func ehColunaPreco(header string) bool {
+	return colunaPrecoRegex.MatchString(header)
+}
+
+func ehColunaBooleana(header string) bool {
+	return header == CAP || header == Confaz87 || header == ICMS0 || header == RestricaoHospitalar || header == Comercializacao2024
+}
+
+func processaValorCelula(value any, header string) any {
 	if header == PrincipioAtivo && value == nil {
 		return ""
 	}
@@ -353,43 +501,65 @@ func processaValorCelula(value any, header string) any {
 
 	if strValue == "-" || strValue == "" {
 		return nil
-	} else if valoresRegex.MatchString(header) && realRegex.MatchString(strValue) {
+	} else if ehColunaPreco(header) && valorRealRegex.MatchString(strValue) {
 		strValue = strings.Replace(strValue, ",", ".", 1)
 		strValue = strings.Replace(strValue, "*", "", 1)
 		floatValue, err := strconv.ParseFloat(strValue, 64)
 		if err == nil {
 			return floatValue
 		}
-	} else if header == CAP || header == Confaz87 || header == ICMS0 || header == RestricaoHospitalar || header == Comercializacao2024 {
+	} else if ehColunaBooleana(header) {
 		return strings.ToLower(strValue) == "sim"
 	}
 
 	return strValue
 }
 
+var comandos = map[string]func(args []string){
+	"ingest":    runIngest,
+	"historico": runHistorico,
+	"reajuste":  runReajuste,
+	"verificar": runVerificar,
+	"schema":    runSchema,
+	"lote":      runLote,
+	"query":     runQuery,
+	"search":    runSearch,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if comando, ok := comandos[os.Args[1]]; ok {
+			comando(os.Args[2:])
+			return
+		}
+	}
+
 	data := flag.String("data", time.Now().Format("2006-01-02"), "Data da planilha no formato AAAA-MM-DD")
 	dataAtualizacao := flag.String("data-atualizacao", "", "Data de atualização da planilha no formato AAAA-MM-DD")
-	zipOutput := flag.Bool("zip", false, "Gerar arquivo zipado ao invés de JSON")
+	saida := registraFlagsSaida(flag.CommandLine)
+	workers := flag.Int("workers", runtime.NumCPU(), "Número de goroutines que processam as linhas da planilha")
+	filtro := flag.String("filter", "", "Expressão que seleciona os medicamentos incluídos na saída")
 	flag.Parse()
 
-	if *dataAtualizacao == "" {
-		*dataAtualizacao = *data
+	dataTime, dataAtualizacaoTime, err := parseDatas(*data, *dataAtualizacao)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// convert data and dataAtualizacao to time.Time to validate the format
-	var dataTime time.Time
-	var dataAtualizacaoTime time.Time
-	var err error
-	if dataTime, err = time.Parse("2006-01-02", *data); err != nil {
-		log.Fatalf("Data inválida: %s. Use o formato AAAA-MM-DD", *data)
+	opcoesSaida, err := saida.opcoes()
+	if err != nil {
+		log.Fatal(err)
 	}
-	if dataAtualizacaoTime, err = time.Parse("2006-01-02", *dataAtualizacao); err != nil {
-		log.Fatalf("Data de atualização inválida: %s. Use o formato AAAA-MM-DD", *dataAtualizacao)
+
+	opcoesProcessamento := OpcoesProcessamento{Workers: *workers}
+	if *filtro != "" {
+		if opcoesProcessamento.Filtro, err = parseFiltroProcessamento(*filtro); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	if len(flag.Args()) != 1 {
-		log.Fatal("Uso: go run main.go [flags] <arquivo.xlsx>")
+		log.Fatal("Uso: go run . [flags] <arquivo.xlsx>")
 	}
 
 	infilePath := flag.Args()[0]
@@ -398,18 +568,16 @@ func main() {
 		log.Fatal("O arquivo de entrada deve ser .xlsx")
 	}
 
-	output, err := processExcelFile(infilePath, dataTime, dataAtualizacaoTime)
+	output, err := processExcelFile(infilePath, dataTime, dataAtualizacaoTime, opcoesProcessamento)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if *zipOutput {
-		if err := writeZipFile(output, infilePath); err != nil {
-			log.Fatal(err)
-		}
-	} else {
-		if err := writeJSONFile(output, infilePath); err != nil {
-			log.Fatal(err)
-		}
+	for _, aviso := range output.Avisos {
+		log.Printf("Aviso: %s", aviso)
+	}
+
+	if err := escreveSaida(output, infilePath, opcoesSaida); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"os"
@@ -106,11 +108,18 @@ func TestWriteJSONFile(t *testing.T) {
 			{"SUBSTÂNCIA": "IBUPROFENO", "CNPJ": "12.345.678/0001-90"},
 			{"SUBSTÂNCIA": "PARACETAMOL", "CNPJ": "98.765.432/0001-10"},
 		},
-		Laboratorios: map[string]string{
-			"12.345.678/0001-90": "LAB A",
-			"98.765.432/0001-10": "LAB B",
+		Laboratorios: map[string]CadastroLaboratorio{
+			"12.345.678/0001-90": {Nome: "LAB A", Variantes: []string{"LAB A"}, Produtos: 1},
+			"98.765.432/0001-10": {Nome: "LAB B", Variantes: []string{"LAB B"}, Produtos: 1},
+		},
+		GruposLaboratorios: map[string][]string{
+			"12.345.678": {"12.345.678/0001-90"},
+			"98.765.432": {"98.765.432/0001-10"},
+		},
+		Apresentacoes: []ApresentacaoCatalogo{
+			{ID: 1, Descricao: "COM REV", Ocorrencias: 1, Variantes: []string{"COM REV"}, CodigosGGREM: []string{"500101101111417"}},
+			{ID: 2, Descricao: "GOTAS", Ocorrencias: 1, Variantes: []string{"GOTAS"}, CodigosGGREM: []string{"538912020000003"}},
 		},
-		Apresentacoes: []string{"COM REV", "GOTAS"},
 	}
 
 	// Write the JSON file
@@ -142,11 +151,18 @@ func TestWriteZipFile(t *testing.T) {
 			{"SUBSTÂNCIA": "IBUPROFENO", "CNPJ": "12.345.678/0001-90"},
 			{"SUBSTÂNCIA": "PARACETAMOL", "CNPJ": "98.765.432/0001-10"},
 		},
-		Laboratorios: map[string]string{
-			"12.345.678/0001-90": "LAB A",
-			"98.765.432/0001-10": "LAB B",
+		Laboratorios: map[string]CadastroLaboratorio{
+			"12.345.678/0001-90": {Nome: "LAB A", Variantes: []string{"LAB A"}, Produtos: 1},
+			"98.765.432/0001-10": {Nome: "LAB B", Variantes: []string{"LAB B"}, Produtos: 1},
+		},
+		GruposLaboratorios: map[string][]string{
+			"12.345.678": {"12.345.678/0001-90"},
+			"98.765.432": {"98.765.432/0001-10"},
+		},
+		Apresentacoes: []ApresentacaoCatalogo{
+			{ID: 1, Descricao: "COM REV", Ocorrencias: 1, Variantes: []string{"COM REV"}, CodigosGGREM: []string{"500101101111417"}},
+			{ID: 2, Descricao: "GOTAS", Ocorrencias: 1, Variantes: []string{"GOTAS"}, CodigosGGREM: []string{"538912020000003"}},
 		},
-		Apresentacoes: []string{"COM REV", "GOTAS"},
 	}
 
 	// Write the JSON file (which is now zipped)
@@ -249,34 +265,55 @@ func TestProcessExcelFile(t *testing.T) {
 	}
 
 	// Write data rows
-	f.SetCellValue(sheetName, "A4", "IBUPROFENO")         // SUBSTÂNCIA
-	f.SetCellValue(sheetName, "B4", "12.345.678/0001-90") // CNPJ
-	f.SetCellValue(sheetName, "C4", "LAB A")              // LABORATÓRIO
-	f.SetCellValue(sheetName, "J4", "COM REV")            // APRESENTAÇÃO
-	f.SetCellValue(sheetName, "N4", "10,50")              // PF Sem Impostos
-	f.SetCellValue(sheetName, "BN4", "Sim")               // RESTRIÇÃO HOSPITALAR
-	f.SetCellValue(sheetName, "BO4", "Não")               // CAP
-	f.SetCellValue(sheetName, "BP4", "Sim")               // CONFAZ 87
+	f.SetCellValue(sheetName, "A4", "IBUPROFENO")                           // SUBSTÂNCIA
+	f.SetCellValue(sheetName, "B4", "12.345.678/0001-90")                   // CNPJ
+	f.SetCellValue(sheetName, "C4", "LAB A")                                // LABORATÓRIO
+	f.SetCellValue(sheetName, "J4", "COM REV")                              // APRESENTAÇÃO
+	f.SetCellValue(sheetName, "K4", "M1A - ANTI-REUMÁTICOS NÃO ESTEROIDES") // CLASSE TERAPÊUTICA
+	f.SetCellValue(sheetName, "L4", "Genérico")                             // TIPO DE PRODUTO
+	f.SetCellValue(sheetName, "M4", "Regulado")                             // REGIME DE PREÇO
+	f.SetCellValue(sheetName, "N4", "10,50")                                // PF Sem Impostos
+	f.SetCellValue(sheetName, "BN4", "Sim")                                 // RESTRIÇÃO HOSPITALAR
+	f.SetCellValue(sheetName, "BO4", "Não")                                 // CAP
+	f.SetCellValue(sheetName, "BP4", "Sim")                                 // CONFAZ 87
+	f.SetCellValue(sheetName, "BS4", "Positiva")                            // LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO
+	f.SetCellValue(sheetName, "BU4", "Tarja Vermelha")                      // TARJA
 
 	f.SetCellValue(sheetName, "A5", "PARACETAMOL")        // SUBSTÂNCIA
 	f.SetCellValue(sheetName, "B5", "98.765.432/0001-10") // CNPJ
 	f.SetCellValue(sheetName, "C5", "LAB B")              // LABORATÓRIO
 	f.SetCellValue(sheetName, "J5", "GOTAS")              // APRESENTAÇÃO
+	f.SetCellValue(sheetName, "L5", "Novíssimo")          // TIPO DE PRODUTO
 	f.SetCellValue(sheetName, "N5", "25,00*")             // PF Sem Impostos
 	f.SetCellValue(sheetName, "BN5", "Não")               // RESTRIÇÃO HOSPITALAR
 	f.SetCellValue(sheetName, "BO5", "Sim")               // CAP
+	f.SetCellValue(sheetName, "BS5", "Negativa")          // LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO
+	f.SetCellValue(sheetName, "BU5", "VENDA LIVRE")       // TARJA
 
 	// Save the temporary file
 	if err := f.SaveAs(infilePath); err != nil {
 		t.Fatalf("failed to save temporary excel file: %v", err)
 	}
 
+	conteudo, err := os.ReadFile(infilePath)
+	if err != nil {
+		t.Fatalf("failed to read temporary excel file: %v", err)
+	}
+	hash := sha256.Sum256(conteudo)
+
 	// Expected output
 	expectedOutput := Output{
 		Metadados: Metadados{
 			Data:            "2025-07-03",
 			DataAtualizacao: "2025-07-04",
 			Observacoes:     []string{"Observação 1", "Observação 2"},
+			VersaoSchema:    versaoSchema,
+			VersaoParser:    versao,
+			Arquivo:         "test.xlsx",
+			SHA256:          hex.EncodeToString(hash[:]),
+			Planilha:        "Sheet1",
+			LinhaCabecalho:  3,
+			Linhas:          2,
 		},
 		Medicamentos: []Medicamento{
 			{
@@ -290,9 +327,9 @@ func TestProcessExcelFile(t *testing.T) {
 				"EAN 3":                               nil,
 				"PRODUTO":                             nil,
 				"APRESENTAÇÃO":                        "COM REV",
-				"CLASSE TERAPÊUTICA":                  nil,
-				"TIPO DE PRODUTO (STATUS DO PRODUTO)": nil,
-				"REGIME DE PREÇO":                     nil,
+				"CLASSE TERAPÊUTICA":                  "M1A - ANTI-REUMÁTICOS NÃO ESTEROIDES",
+				"TIPO DE PRODUTO (STATUS DO PRODUTO)": "Genérico",
+				"REGIME DE PREÇO":                     "Regulado",
 				"PF Sem Impostos":                     10.50,
 				"PF 0%":                               nil,
 				"PF 12%":                              nil,
@@ -350,9 +387,26 @@ func TestProcessExcelFile(t *testing.T) {
 				"CONFAZ 87":                           true,
 				"ICMS 0%":                             nil,
 				"ANÁLISE RECURSAL":                    nil,
-				"LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO (PIS/COFINS)": nil,
+				"LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO (PIS/COFINS)": "Positiva",
 				"COMERCIALIZAÇÃO 2024":                                  nil,
-				"TARJA":                                                 nil,
+				"TARJA":                                                 "Tarja Vermelha",
+				"substancias":                                           []string{"IBUPROFENO"},
+				"tipoProduto":                                           TipoProdutoGenerico,
+				"regimePreco":                                           RegimeRegulado,
+				"tarja":                                                 TarjaVermelha,
+				"listaPisCofins":                                        ListaPositiva,
+				"precosMarcados":                                        []string{},
+				"apresentacaoId":                                        1,
+				"apresentacaoDetalhes":                                  DetalhesApresentacao{Forma: "COM REV"},
+				"grupoEquivalenciaId":                                   nil,
+				"precosUnitarios":                                       nil,
+				"quantidadeIndeterminada":                               true,
+				"linha":                                                 4,
+				"classificacaoTerapeutica": ClassificacaoTerapeutica{
+					Codigo:    "M1A",
+					Descricao: "ANTI-REUMÁTICOS NÃO ESTEROIDES",
+					Niveis:    []string{"M", "M1", "M1A"},
+				},
 			},
 			{
 				"SUBSTÂNCIA":                          "PARACETAMOL",
@@ -366,7 +420,7 @@ func TestProcessExcelFile(t *testing.T) {
 				"PRODUTO":                             nil,
 				"APRESENTAÇÃO":                        "GOTAS",
 				"CLASSE TERAPÊUTICA":                  nil,
-				"TIPO DE PRODUTO (STATUS DO PRODUTO)": nil,
+				"TIPO DE PRODUTO (STATUS DO PRODUTO)": "Novíssimo",
 				"REGIME DE PREÇO":                     nil,
 				"PF Sem Impostos":                     25.00,
 				"PF 0%":                               nil,
@@ -425,16 +479,57 @@ func TestProcessExcelFile(t *testing.T) {
 				"CONFAZ 87":                           nil,
 				"ICMS 0%":                             nil,
 				"ANÁLISE RECURSAL":                    nil,
-				"LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO (PIS/COFINS)": nil,
+				"LISTA DE CONCESSÃO DE CRÉDITO TRIBUTÁRIO (PIS/COFINS)": "Negativa",
 				"COMERCIALIZAÇÃO 2024":                                  nil,
-				"TARJA":                                                 nil,
+				"TARJA":                                                 "VENDA LIVRE",
+				"substancias":                                           []string{"PARACETAMOL"},
+				"tipoProduto":                                           TipoProdutoDesconhecido,
+				"regimePreco":                                           nil,
+				"tarja":                                                 TarjaVendaLivre,
+				"listaPisCofins":                                        ListaNegativa,
+				"precosMarcados":                                        []string{"PF Sem Impostos"},
+				"apresentacaoId":                                        2,
+				"apresentacaoDetalhes":                                  DetalhesApresentacao{Forma: "GOTAS"},
+				"grupoEquivalenciaId":                                   nil,
+				"precosUnitarios":                                       nil,
+				"quantidadeIndeterminada":                               true,
+				"linha":                                                 5,
+				"classificacaoTerapeutica":                              nil,
+			},
+		},
+		Laboratorios: map[string]CadastroLaboratorio{
+			"12.345.678/0001-90": {Nome: "LAB A", Variantes: []string{"LAB A"}, Produtos: 1},
+			"98.765.432/0001-10": {Nome: "LAB B", Variantes: []string{"LAB B"}, Produtos: 1},
+		},
+		GruposLaboratorios: map[string][]string{
+			"12.345.678": {"12.345.678/0001-90"},
+			"98.765.432": {"98.765.432/0001-10"},
+		},
+		Apresentacoes: []ApresentacaoCatalogo{
+			{ID: 1, Descricao: "COM REV", Ocorrencias: 1, Variantes: []string{"COM REV"}, CodigosGGREM: []string{}},
+			{ID: 2, Descricao: "GOTAS", Ocorrencias: 1, Variantes: []string{"GOTAS"}, CodigosGGREM: []string{}},
+		},
+		Substancias: map[string][]string{
+			"IBUPROFENO":  {},
+			"PARACETAMOL": {},
+		},
+		ClassesTerapeuticas: map[string]string{
+			"M1A": "ANTI-REUMÁTICOS NÃO ESTEROIDES",
+		},
+		ResumoTributario: ResumoTributario{
+			PorLista: map[ListaPISCOFINS]int{
+				ListaPositiva: 1,
+				ListaNegativa: 1,
+			},
+			PorLaboratorio: map[string]map[ListaPISCOFINS]int{
+				"12.345.678/0001-90": {ListaPositiva: 1},
+				"98.765.432/0001-10": {ListaNegativa: 1},
 			},
 		},
-		Laboratorios: map[string]string{
-			"12.345.678/0001-90": "LAB A",
-			"98.765.432/0001-10": "LAB B",
+		GruposEquivalencia: []GrupoEquivalencia{},
+		Avisos: []string{
+			"valor desconhecido na linha 5 e coluna TIPO DE PRODUTO (STATUS DO PRODUTO): 'Novíssimo'",
 		},
-		Apresentacoes: []string{"COM REV", "GOTAS"},
 	}
 
 	// Parse the date strings to time.Time
@@ -447,7 +542,7 @@ func TestProcessExcelFile(t *testing.T) {
 		t.Fatalf("failed to parse dataAtualizacao: %v", err)
 	}
 	// Process the file
-	output, err := processExcelFile(infilePath, data, dataAtualizacao)
+	output, err := processExcelFile(infilePath, data, dataAtualizacao, OpcoesProcessamento{})
 	if err != nil {
 		t.Fatalf("processExcelFile failed: %v", err)
 	}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"cmed-parser/busca"
+)
+
+// camposBusca are the columns indexed for full-text search.
+var camposBusca = []string{Produto, PrincipioAtivo, Apresentacao, Laboratorio}
+
+// origemIndice identifies the medicines of an output: the spreadsheet they
+// come from, the filter that selected them and their order, since document
+// numbers are positions.
+func origemIndice(metadados Metadados) map[string]string {
+	ordem := "planilha"
+	if metadados.Canonico {
+		ordem = "canonica"
+	}
+	return map[string]string{"sha256": metadados.SHA256, "filtro": metadados.Filtro, "ordem": ordem}
+}
+
+// indexaOutput builds the search index of the medicines of the output.
+// Document numbers are the positions of the medicines in the list.
+func indexaOutput(output Output) *busca.Indice {
+	indice := busca.Novo(camposBusca...)
+	indice.Origem = origemIndice(output.Metadados)
+	textos := make([]string, len(camposBusca))
+	for _, medicamento := range output.Medicamentos {
+		for i, campo := range camposBusca {
+			textos[i] = textoCampo(medicamento, campo)
+		}
+		indice.Adiciona(textos...)
+	}
+	return indice
+}
+
+// caminhoIndice returns the path of the search index kept next to an input
+// or output file.
+func caminhoIndice(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".indice.json.gz"
+}
+
+func writeIndiceBusca(indice *busca.Indice, infilePath string) error {
+	outfilePath := caminhoIndice(infilePath)
+	indiceFile, err := os.Create(outfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %w", err)
+	}
+	defer indiceFile.Close()
+
+	if err := indice.Salva(indiceFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Arquivo %s criado!\n", outfilePath)
+	return nil
+}
+
+// carregaIndiceBusca loads the index saved next to the file, or builds it
+// when there is none or when it was built from other medicines or in
+// another order.
+func carregaIndiceBusca(path string, output Output) (*busca.Indice, error) {
+	f, err := os.Open(caminhoIndice(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return indexaOutput(output), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer f.Close()
+
+	indice, err := busca.Carrega(f)
+	if err != nil {
+		return nil, err
+	}
+	if indice.Documentos != len(output.Medicamentos) || !maps.Equal(indice.Origem, origemIndice(output.Metadados)) {
+		log.Printf("Aviso: o índice %s não corresponde a %s e será reconstruído", caminhoIndice(path), path)
+		return indexaOutput(output), nil
+	}
+	return indice, nil
+}
+
+// ResultadoBusca is a medicine found by the search command.
+type ResultadoBusca struct {
+	Pontos      int         `json:"pontos"`
+	Campos      []string    `json:"campos"`
+	Medicamento Medicamento `json:"medicamento"`
+}
+
+func runSearch(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	prefixo := flags.Bool("prefixo", true, "Encontrar também palavras que começam com os termos da busca")
+	distancia := flags.Int("distancia", 1, "Número máximo de letras diferentes em correspondências aproximadas (0 desativa)")
+	limite := flags.Int("limite", 20, "Número máximo de resultados (0 exibe todos)")
+	formato := flags.String("format", "tabela", "Formato da saída: tabela ou json")
+	icms := flags.String("icms", "18%", "Alíquota de ICMS dos preços exibidos na tabela")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		log.Fatal("Uso: cmed-parser search [flags] <termos> <arquivo.xlsx|json|zip>")
+	}
+	if *formato != "tabela" && *formato != "json" {
+		log.Fatalf("formato desconhecido: %s. Use tabela ou json", *formato)
+	}
+	if !strings.HasSuffix(*icms, "%") {
+		*icms += "%"
+	}
+	if !slices.Contains(cabecalho, "PF "+*icms) {
+		log.Fatalf("alíquota de ICMS desconhecida: %s", *icms)
+	}
+
+	path := flags.Arg(1)
+	hoje := time.Now()
+	output, err := carregaOutput(path, hoje, hoje)
+	if err != nil {
+		log.Fatal(err)
+	}
+	indice, err := carregaIndiceBusca(path, output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resultados := indice.Busca(flags.Arg(0), busca.Opcoes{Prefixo: *prefixo, Distancia: *distancia, Limite: *limite})
+	encontrados := make([]ResultadoBusca, len(resultados))
+	medicamentos := make([]Medicamento, len(resultados))
+	for i, resultado := range resultados {
+		medicamentos[i] = output.Medicamentos[resultado.Documento]
+		encontrados[i] = ResultadoBusca{Pontos: resultado.Pontos, Campos: resultado.Campos, Medicamento: medicamentos[i]}
+	}
+
+	if *formato == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(encontrados)
+	} else {
+		err = writeTabelaConsulta(os.Stdout, medicamentos, *icms)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d medicamentos encontrados", len(resultados))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cmed-parser/busca"
+)
+
+func TestIndiceBusca(t *testing.T) {
+	tempDir := t.TempDir()
+	infilePath := filepath.Join(tempDir, "lista.xlsx")
+
+	output := Output{
+		Metadados: Metadados{Data: "2025-07-03", Observacoes: []string{}, SHA256: "abc"},
+		Medicamentos: []Medicamento{
+			{CodigoGGREM: "2", Produto: "NOVALGINA", PrincipioAtivo: "DIPIRONA", Apresentacao: "500 MG/ML SOL GOT", Laboratorio: "SANOFI"},
+			{CodigoGGREM: "1", Produto: "DORFLEX", PrincipioAtivo: "DIPIRONA;CAFEÍNA", Apresentacao: "COM CT X 10", Laboratorio: "SANOFI"},
+			{CodigoGGREM: "3", Produto: "AMOXIL", PrincipioAtivo: "AMOXICILINA", Apresentacao: nil, Laboratorio: "GSK"},
+		},
+	}
+	opcoes := OpcoesSaida{Canonico: true, Indice: true, Projecao: Projecao{Colunas: []string{CodigoGGREM}}}
+	if err := escreveSaida(output, infilePath, opcoes); err != nil {
+		t.Fatalf("escreveSaida failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "lista.indice.json.gz")); err != nil {
+		t.Fatalf("index file not written: %v", err)
+	}
+
+	outfilePath := filepath.Join(tempDir, "lista.json")
+	gerado, err := carregaOutput(outfilePath, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("carregaOutput failed: %v", err)
+	}
+	indice, err := carregaIndiceBusca(outfilePath, gerado)
+	if err != nil {
+		t.Fatalf("carregaIndiceBusca failed: %v", err)
+	}
+	if expected := origemIndice(gerado.Metadados); !reflect.DeepEqual(indice.Origem, expected) || expected["ordem"] != "canonica" {
+		t.Errorf("esperado: %v, obtido: %v", expected, indice.Origem)
+	}
+
+	// The index refers to the canonical order and to the columns removed
+	// by the projection.
+	testCases := []struct {
+		name     string
+		consulta string
+		ggrem    string
+		campos   []string
+	}{
+		{"ordem canônica", "cafeina", "1", []string{"SUBSTÂNCIA"}},
+		{"colunas removidas", "dorflex sanofi dipirona", "1", []string{"PRODUTO", "SUBSTÂNCIA", "LABORATÓRIO"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resultados := indice.Busca(tc.consulta, busca.Opcoes{})
+			if len(resultados) != 1 {
+				t.Fatalf("esperado: 1 resultado, obtido: %+v", resultados)
+			}
+			if result := gerado.Medicamentos[resultados[0].Documento][CodigoGGREM]; result != tc.ggrem {
+				t.Errorf("esperado: %v, obtido: %v", tc.ggrem, result)
+			}
+			if !reflect.DeepEqual(resultados[0].Campos, tc.campos) {
+				t.Errorf("esperado: %v, obtido: %v", tc.campos, resultados[0].Campos)
+			}
+		})
+	}
+}
+
+func TestIndiceBuscaReconstruido(t *testing.T) {
+	tempDir := t.TempDir()
+	infilePath := filepath.Join(tempDir, "lista.xlsx")
+	outfilePath := filepath.Join(tempDir, "lista.json")
+
+	novoOutput := func() Output {
+		return Output{
+			Metadados: Metadados{Data: "2025-07-03", Observacoes: []string{}, SHA256: "abc"},
+			Medicamentos: []Medicamento{
+				{CodigoGGREM: "2", Produto: "NOVALGINA"},
+				{CodigoGGREM: "1", Produto: "DORFLEX"},
+			},
+		}
+	}
+	if err := escreveSaida(novoOutput(), infilePath, OpcoesSaida{Indice: true}); err != nil {
+		t.Fatalf("escreveSaida failed: %v", err)
+	}
+	f, err := os.Open(caminhoIndice(outfilePath))
+	if err != nil {
+		t.Fatalf("failed to open index file: %v", err)
+	}
+	defer f.Close()
+	salvo, err := busca.Carrega(f)
+	if err != nil {
+		t.Fatalf("Carrega failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		altera   func(output *Output)
+		expected bool
+	}{
+		{"mesma origem", func(output *Output) {}, false},
+		{"outra planilha", func(output *Output) { output.Metadados.SHA256 = "def" }, true},
+		{"ordem canônica", func(output *Output) { ordenaOutput(output); output.Metadados.Canonico = true }, true},
+		{"outro filtro", func(output *Output) { output.Metadados.Filtro = "PF18 > 10" }, true},
+		{"outros medicamentos", func(output *Output) { output.Medicamentos = output.Medicamentos[:1] }, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := novoOutput()
+			tc.altera(&output)
+			indice, err := carregaIndiceBusca(outfilePath, output)
+			if err != nil {
+				t.Fatalf("carregaIndiceBusca failed: %v", err)
+			}
+			if result := !reflect.DeepEqual(indice, salvo); result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+			if expected := indexaOutput(output); !reflect.DeepEqual(indice.Origem, expected.Origem) || indice.Documentos != expected.Documentos {
+				t.Errorf("esperado: %+v, obtido: %+v", expected, indice)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tamanhoLote is the number of rows handed to a worker at a time, so the
+// channel overhead stays small compared to the cleaning work.
+const tamanhoLote = 256
+
+// OpcoesProcessamento configures the processing of a spreadsheet. The zero
+// value uses the defaults.
+type OpcoesProcessamento struct {
+	// Workers is the number of goroutines cleaning rows; zero or less uses
+	// one per CPU.
+	Workers int
+	// Filtro selects the medicines kept; nil keeps all of them.
+	Filtro *Filtro
+}
+
+func (o OpcoesProcessamento) workers() int {
+	if o.Workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return o.Workers
+}
+
+// fonteLinhas returns the next row of the sheet, or false when there are no
+// more rows.
+type fonteLinhas func() ([]string, bool, error)
+
+func linhasSlice(rows [][]string) fonteLinhas {
+	i := 0
+	return func() ([]string, bool, error) {
+		if i >= len(rows) {
+			return nil, false, nil
+		}
+		i++
+		return rows[i-1], true, nil
+	}
+}
+
+// linhasExcel streams the rows of a sheet. Like excelize's GetRows, empty
+// rows are kept only when a non-empty row follows them.
+func linhasExcel(rows *excelize.Rows) fonteLinhas {
+	vazias := 0
+	var pendente []string
+	return func() ([]string, bool, error) {
+		if vazias == 0 && pendente == nil {
+			for rows.Next() {
+				celulas, err := rows.Columns()
+				if err != nil {
+					return nil, false, err
+				}
+				if len(celulas) > 0 {
+					pendente = celulas
+					break
+				}
+				vazias++
+			}
+			if pendente == nil {
+				return nil, false, rows.Error()
+			}
+		}
+		if vazias > 0 {
+			vazias--
+			return nil, true, nil
+		}
+		linha := pendente
+		pendente = nil
+		return linha, true, nil
+	}
+}
+
+// valorDesconhecido is an enum value missing from the mapping tables, found
+// by a worker and reported in row order by the consumer.
+type valorDesconhecido struct {
+	coluna string
+	valor  any
+}
+
+type loteLinhas struct {
+	sequencia int
+	inicio    int // spreadsheet row number of the first row
+	linhas    [][]string
+}
+
+type loteMedicamentos struct {
+	sequencia     int
+	medicamentos  []Medicamento
+	desconhecidos [][]valorDesconhecido
+}
+
+// processaLinha cleans one data row and derives the per-row fields.
+func processaLinha(numero int, row []string) (Medicamento, []valorDesconhecido) {
+	medicamento := Medicamento{Linha: numero}
+	precosMarcados := []string{}
+	for j, header := range cabecalho {
+		var value string
+		if j < len(row) {
+			value = strings.TrimSpace(row[j])
+		}
+
+		medicamento[header] = processaValorCelula(value, header)
+
+		// A trailing asterisk marks prices under specific conditions.
+		if _, ok := medicamento[header].(float64); ok && strings.HasSuffix(value, "*") {
+			precosMarcados = append(precosMarcados, header)
+		}
+	}
+	medicamento[PrecosMarcados] = precosMarcados
+
+	substancias := []string{}
+	if medicamento[PrincipioAtivo] != nil {
+		substancias = separaSubstancias(medicamento[PrincipioAtivo].(string))
+	}
+	medicamento[Substancias] = substancias
+
+	medicamento[ApresentacaoDetalhes] = nil
+	medicamento[PrecosUnitarios] = nil
+	if medicamento[Apresentacao] != nil {
+		detalhes := parseApresentacao(medicamento[Apresentacao].(string))
+		medicamento[ApresentacaoDetalhes] = detalhes
+		if precos := precosUnitarios(medicamento, detalhes); precos != nil {
+			medicamento[PrecosUnitarios] = precos
+		}
+	}
+	medicamento[QuantidadeIndeterminada] = medicamento[PrecosUnitarios] == nil
+
+	medicamento[Classificacao] = nil
+	if medicamento[ClasseTerapeutica] != nil {
+		if classe, ok := parseClasseTerapeutica(medicamento[ClasseTerapeutica].(string)); ok {
+			medicamento[Classificacao] = classe
+		}
+	}
+
+	var desconhecidos []valorDesconhecido
+	var ok bool
+	if medicamento[TipoProdutoCodigo], ok = codigoEnum(medicamento[Tipo], tiposProduto, TipoProdutoDesconhecido); !ok {
+		desconhecidos = append(desconhecidos, valorDesconhecido{Tipo, medicamento[Tipo]})
+	}
+	if medicamento[RegimePrecoCodigo], ok = codigoEnum(medicamento[RegimePreco], regimesPreco, RegimeDesconhecido); !ok {
+		desconhecidos = append(desconhecidos, valorDesconhecido{RegimePreco, medicamento[RegimePreco]})
+	}
+	if medicamento[TarjaCodigo], ok = codigoEnum(medicamento[Tarja], tarjas, TarjaDesconhecida); !ok {
+		desconhecidos = append(desconhecidos, valorDesconhecido{Tarja, medicamento[Tarja]})
+	}
+	if medicamento[ListaPISCOFINSCodigo], ok = codigoEnum(medicamento[ListaConcessaoCreditoTributario], listasPISCOFINS, ListaDesconhecida); !ok {
+		desconhecidos = append(desconhecidos, valorDesconhecido{ListaConcessaoCreditoTributario, medicamento[ListaConcessaoCreditoTributario]})
+	}
+
+	return medicamento, desconhecidos
+}
+
+// processaLinhasConcorrente runs the row pipeline: one goroutine reads the
+// rows that follow the header from proxima, a pool of workers cleans and
+// filters them in batches, and consome receives the medicines kept back in
+// spreadsheet order. primeira is the spreadsheet row number of the first
+// row read. It returns the number of rows read, including those filtered
+// out.
+func processaLinhasConcorrente(proxima fonteLinhas, primeira int, opcoes OpcoesProcessamento, consome func(Medicamento, []valorDesconhecido)) (int, error) {
+	workers := opcoes.workers()
+	entrada := make(chan loteLinhas, workers)
+	saida := make(chan loteMedicamentos, workers)
+
+	var erroLeitura error
+	lidas := 0
+	go func() {
+		defer close(entrada)
+		lote := loteLinhas{inicio: primeira}
+		numero := primeira
+		for {
+			row, ok, err := proxima()
+			if err != nil {
+				erroLeitura = err
+				return
+			}
+			if !ok {
+				break
+			}
+			lote.linhas = append(lote.linhas, row)
+			numero++
+			lidas++
+			if len(lote.linhas) == tamanhoLote {
+				entrada <- lote
+				lote = loteLinhas{sequencia: lote.sequencia + 1, inicio: numero}
+			}
+		}
+		if len(lote.linhas) > 0 {
+			entrada <- lote
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lote := range entrada {
+				resultado := loteMedicamentos{
+					sequencia:     lote.sequencia,
+					medicamentos:  make([]Medicamento, len(lote.linhas)),
+					desconhecidos: make([][]valorDesconhecido, len(lote.linhas)),
+				}
+				for i, row := range lote.linhas {
+					medicamento, desconhecidos := processaLinha(lote.inicio+i, row)
+					if opcoes.Filtro.aceita(medicamento) {
+						resultado.medicamentos[i], resultado.desconhecidos[i] = medicamento, desconhecidos
+					}
+				}
+				saida <- resultado
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(saida)
+	}()
+
+	// Batches finish out of order; hold them until their turn comes.
+	pendentes := make(map[int]loteMedicamentos)
+	proximo := 0
+	for resultado := range saida {
+		pendentes[resultado.sequencia] = resultado
+		for {
+			lote, ok := pendentes[proximo]
+			if !ok {
+				break
+			}
+			delete(pendentes, proximo)
+			for i, medicamento := range lote.medicamentos {
+				if medicamento != nil {
+					consome(medicamento, lote.desconhecidos[i])
+				}
+			}
+			proximo++
+		}
+	}
+
+	return lidas, erroLeitura
+}
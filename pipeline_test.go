@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestProcessRowsIndependeDeWorkers(t *testing.T) {
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	rows := linhasSinteticas(3*tamanhoLote + 17)
+	// Unknown values must be reported once, for the first row where they
+	// appear, whatever the worker that cleaned it.
+	rows[5][11] = "Novíssimo"
+	rows[600][11] = "Novíssimo"
+	rows[400][72] = "Tarja Roxa"
+
+	expected, err := processRows(rows, data, data, OpcoesProcessamento{Workers: 1})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+	expectedAvisos := []string{
+		"valor desconhecido na linha 6 e coluna TIPO DE PRODUTO (STATUS DO PRODUTO): 'Novíssimo'",
+		"valor desconhecido na linha 401 e coluna TARJA: 'Tarja Roxa'",
+	}
+	// The synthetic laboratories also produce CNPJ warnings, which come last.
+	if len(expected.Avisos) < 2 || !reflect.DeepEqual(expected.Avisos[:2], expectedAvisos) {
+		t.Errorf("esperado: %q, obtido: %q", expectedAvisos, expected.Avisos[:min(2, len(expected.Avisos))])
+	}
+
+	testCases := []struct {
+		name    string
+		workers int
+	}{
+		{"2 workers", 2},
+		{"3 workers", 3},
+		{"8 workers", 8},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := processRows(rows, data, data, OpcoesProcessamento{Workers: tc.workers})
+			if err != nil {
+				t.Fatalf("processRows failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("esperado: saída igual à sequencial, obtido: %d medicamentos e avisos %q", len(result.Medicamentos), result.Avisos)
+			}
+		})
+	}
+}
+
+func TestLinhasExcel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "linhas.xlsx")
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	f.SetCellValue(sheet, "A1", "primeira")
+	f.SetCellValue(sheet, "B3", "terceira")
+	// A formatted row without values must not become a trailing empty row.
+	f.SetRowHeight(sheet, 5, 30)
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("failed to save spreadsheet: %v", err)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatalf("failed to open spreadsheet: %v", err)
+	}
+	expected, err := f.GetRows(sheet)
+	if err != nil {
+		t.Fatalf("GetRows failed: %v", err)
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	defer rows.Close()
+
+	var result [][]string
+	proxima := linhasExcel(rows)
+	for {
+		row, ok, err := proxima()
+		if err != nil {
+			t.Fatalf("linhasExcel failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		result = append(result, row)
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("esperado: %d linhas, obtido: %d", len(expected), len(result))
+	}
+	for i := range expected {
+		if len(result[i]) != len(expected[i]) || (len(result[i]) > 0 && !reflect.DeepEqual(result[i], expected[i])) {
+			t.Errorf("linha %d: esperado: %q, obtido: %q", i+1, expected[i], result[i])
+		}
+	}
+}
+
+func BenchmarkProcessRowsWorkers(b *testing.B) {
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	rows := linhasSinteticas(100000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := processRows(rows, data, data, OpcoesProcessamento{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(rows)), "ns/linha")
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ReajusteProduto struct {
+	CodigoGGREM   string  `json:"codigoGgrem"`
+	Produto       string  `json:"produto"`
+	Apresentacao  string  `json:"apresentacao"`
+	CNPJ          string  `json:"cnpj"`
+	Laboratorio   string  `json:"laboratorio"`
+	Classe        string  `json:"classe"`
+	PrecoAnterior float64 `json:"precoAnterior"`
+	PrecoAtual    float64 `json:"precoAtual"`
+	Variacao      float64 `json:"variacao"`
+	AcimaDoTeto   bool    `json:"acimaDoTeto"`
+}
+
+type ReajusteGrupo struct {
+	Codigo         string  `json:"codigo"`
+	Descricao      string  `json:"descricao"`
+	Produtos       int     `json:"produtos"`
+	VariacaoMedia  float64 `json:"variacaoMedia"`
+	VariacaoMinima float64 `json:"variacaoMinima"`
+	VariacaoMaxima float64 `json:"variacaoMaxima"`
+	AcimaDoTeto    int     `json:"acimaDoTeto"`
+}
+
+type RelatorioReajuste struct {
+	DataAnterior string            `json:"dataAnterior"`
+	DataAtual    string            `json:"dataAtual"`
+	Teto         float64           `json:"teto"`
+	Produtos     []ReajusteProduto `json:"produtos"`
+	Laboratorios []ReajusteGrupo   `json:"laboratorios"`
+	Classes      []ReajusteGrupo   `json:"classes"`
+}
+
+// calculaReajuste compares the PF Sem Impostos of the medicines present in
+// both releases, matched by GGREM code. Changes are percentages, and teto is
+// the maximum authorized adjustment, also in percent.
+func calculaReajuste(anterior, atual Output, teto float64) RelatorioReajuste {
+	precosAnteriores := make(map[string]float64)
+	for _, medicamento := range anterior.Medicamentos {
+		ggrem, okGGREM := medicamento[CodigoGGREM].(string)
+		preco, okPreco := medicamento[PFSemImpostos].(float64)
+		if okGGREM && okPreco && preco > 0 {
+			precosAnteriores[ggrem] = preco
+		}
+	}
+
+	relatorio := RelatorioReajuste{
+		DataAnterior: anterior.Metadados.Data,
+		DataAtual:    atual.Metadados.Data,
+		Teto:         teto,
+		Produtos:     []ReajusteProduto{},
+	}
+	laboratorios := make(map[string]*ReajusteGrupo)
+	classes := make(map[string]*ReajusteGrupo)
+
+	for _, medicamento := range atual.Medicamentos {
+		ggrem, _ := medicamento[CodigoGGREM].(string)
+		precoAnterior, ok := precosAnteriores[ggrem]
+		if !ok {
+			continue
+		}
+		precoAtual, ok := medicamento[PFSemImpostos].(float64)
+		if !ok {
+			continue
+		}
+
+		produto := ReajusteProduto{
+			CodigoGGREM:   ggrem,
+			PrecoAnterior: precoAnterior,
+			PrecoAtual:    precoAtual,
+			// Rounded so that floating point noise does not push a product
+			// authorized exactly at the ceiling above it.
+			Variacao: math.Round((precoAtual/precoAnterior-1)*100*1e4) / 1e4,
+		}
+		produto.AcimaDoTeto = produto.Variacao > teto
+		produto.Produto, _ = medicamento[Produto].(string)
+		produto.Apresentacao, _ = medicamento[Apresentacao].(string)
+		produto.CNPJ, _ = medicamento[CNPJ].(string)
+		produto.Laboratorio, _ = medicamento[Laboratorio].(string)
+
+		var classe ClassificacaoTerapeutica
+		if valor, ok := medicamento[ClasseTerapeutica].(string); ok {
+			classe, _ = parseClasseTerapeutica(valor)
+		}
+		produto.Classe = classe.Codigo
+
+		relatorio.Produtos = append(relatorio.Produtos, produto)
+		if produto.CNPJ != "" {
+			acumulaReajuste(laboratorios, produto.CNPJ, produto.Laboratorio, produto)
+		}
+		if classe.Codigo != "" {
+			acumulaReajuste(classes, classe.Codigo, classe.Descricao, produto)
+		}
+	}
+
+	sort.Slice(relatorio.Produtos, func(i, j int) bool {
+		return relatorio.Produtos[i].CodigoGGREM < relatorio.Produtos[j].CodigoGGREM
+	})
+	relatorio.Laboratorios = finalizaGruposReajuste(laboratorios)
+	relatorio.Classes = finalizaGruposReajuste(classes)
+	return relatorio
+}
+
+func acumulaReajuste(grupos map[string]*ReajusteGrupo, codigo, descricao string, produto ReajusteProduto) {
+	grupo, ok := grupos[codigo]
+	if !ok {
+		grupo = &ReajusteGrupo{
+			Codigo:         codigo,
+			Descricao:      descricao,
+			VariacaoMinima: produto.Variacao,
+			VariacaoMaxima: produto.Variacao,
+		}
+		grupos[codigo] = grupo
+	}
+	grupo.Produtos++
+	// VariacaoMedia holds the sum until finalizaGruposReajuste.
+	grupo.VariacaoMedia += produto.Variacao
+	grupo.VariacaoMinima = min(grupo.VariacaoMinima, produto.Variacao)
+	grupo.VariacaoMaxima = max(grupo.VariacaoMaxima, produto.Variacao)
+	if produto.AcimaDoTeto {
+		grupo.AcimaDoTeto++
+	}
+}
+
+func finalizaGruposReajuste(grupos map[string]*ReajusteGrupo) []ReajusteGrupo {
+	resultado := make([]ReajusteGrupo, 0, len(grupos))
+	for _, grupo := range grupos {
+		grupo.VariacaoMedia = math.Round(grupo.VariacaoMedia/float64(grupo.Produtos)*1e4) / 1e4
+		resultado = append(resultado, *grupo)
+	}
+	sort.Slice(resultado, func(i, j int) bool {
+		return resultado[i].Codigo < resultado[j].Codigo
+	})
+	return resultado
+}
+
+func formataNumeroCSV(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeReajusteProdutosCSV(w io.Writer, produtos []ReajusteProduto) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{CodigoGGREM, Produto, Apresentacao, CNPJ, Laboratorio, ClasseTerapeutica, "PREÇO ANTERIOR", "PREÇO ATUAL", "VARIAÇÃO (%)", "ACIMA DO TETO"})
+	for _, produto := range produtos {
+		writer.Write([]string{
+			produto.CodigoGGREM,
+			produto.Produto,
+			produto.Apresentacao,
+			produto.CNPJ,
+			produto.Laboratorio,
+			produto.Classe,
+			formataNumeroCSV(produto.PrecoAnterior),
+			formataNumeroCSV(produto.PrecoAtual),
+			formataNumeroCSV(produto.Variacao),
+			strconv.FormatBool(produto.AcimaDoTeto),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeReajusteGruposCSV(w io.Writer, grupos []ReajusteGrupo) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"CÓDIGO", "DESCRIÇÃO", "PRODUTOS", "VARIAÇÃO MÉDIA (%)", "VARIAÇÃO MÍNIMA (%)", "VARIAÇÃO MÁXIMA (%)", "ACIMA DO TETO"})
+	for _, grupo := range grupos {
+		writer.Write([]string{
+			grupo.Codigo,
+			grupo.Descricao,
+			strconv.Itoa(grupo.Produtos),
+			formataNumeroCSV(grupo.VariacaoMedia),
+			formataNumeroCSV(grupo.VariacaoMinima),
+			formataNumeroCSV(grupo.VariacaoMaxima),
+			strconv.Itoa(grupo.AcimaDoTeto),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeRelatorioReajuste(relatorio RelatorioReajuste, prefixo string) error {
+	jsonFile, err := os.Create(prefixo + ".json")
+	if err != nil {
+		return fmt.Errorf("failed to create json file: %w", err)
+	}
+	defer jsonFile.Close()
+
+	encoder := json.NewEncoder(jsonFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(relatorio); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	fmt.Printf("Arquivo %s criado!\n", jsonFile.Name())
+
+	csvFiles := []struct {
+		sufixo string
+		write  func(io.Writer) error
+	}{
+		{"-produtos.csv", func(w io.Writer) error { return writeReajusteProdutosCSV(w, relatorio.Produtos) }},
+		{"-laboratorios.csv", func(w io.Writer) error { return writeReajusteGruposCSV(w, relatorio.Laboratorios) }},
+		{"-classes.csv", func(w io.Writer) error { return writeReajusteGruposCSV(w, relatorio.Classes) }},
+	}
+	for _, csvFile := range csvFiles {
+		f, err := os.Create(prefixo + csvFile.sufixo)
+		if err != nil {
+			return fmt.Errorf("failed to create csv file: %w", err)
+		}
+		if err := csvFile.write(f); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write csv file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close csv file: %w", err)
+		}
+		fmt.Printf("Arquivo %s criado!\n", f.Name())
+	}
+	return nil
+}
+
+func runReajuste(args []string) {
+	flags := flag.NewFlagSet("reajuste", flag.ExitOnError)
+	teto := flags.Float64("teto", -1, "Reajuste máximo autorizado, em porcentagem (por exemplo, 4.5)")
+	saida := flags.String("saida", "reajuste", "Prefixo dos arquivos de saída")
+	dataAnterior := flags.String("data-anterior", "", "Data da planilha anterior no formato AAAA-MM-DD (obrigatória para arquivos .xlsx)")
+	dataAtual := flags.String("data-atual", "", "Data da planilha atual no formato AAAA-MM-DD (obrigatória para arquivos .xlsx)")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 || *teto < 0 {
+		log.Fatal("Uso: cmed-parser reajuste --teto <porcentagem> [flags] <anterior.xlsx|json|zip> <atual.xlsx|json|zip>")
+	}
+
+	var versoes [2]Output
+	datas := []struct{ flag, valor string }{{"--data-anterior", *dataAnterior}, {"--data-atual", *dataAtual}}
+	for i, data := range datas {
+		// Spreadsheets carry no date of their own, so defaulting to today
+		// would give both releases the same date.
+		var dataTime time.Time
+		if data.valor != "" {
+			var err error
+			if dataTime, _, err = parseDatas(data.valor, ""); err != nil {
+				log.Fatal(err)
+			}
+		} else if strings.EqualFold(filepath.Ext(flags.Arg(i)), ".xlsx") {
+			log.Fatalf("Informe a data da planilha %s com a flag %s", flags.Arg(i), data.flag)
+		}
+		var err error
+		if versoes[i], err = carregaOutput(flags.Arg(i), dataTime, dataTime); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	relatorio := calculaReajuste(versoes[0], versoes[1], *teto)
+	if err := writeRelatorioReajuste(relatorio, *saida); err != nil {
+		log.Fatal(err)
+	}
+}
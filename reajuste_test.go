@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCalculaReajuste(t *testing.T) {
+	anterior := Output{
+		Metadados: Metadados{Data: "2024-04-01"},
+		Medicamentos: []Medicamento{
+			{CodigoGGREM: "2", PFSemImpostos: 200.0, CNPJ: "12.345.678/0001-90", Laboratorio: "LAB A", ClasseTerapeutica: "M1A - ANTI-REUMÁTICOS"},
+			{CodigoGGREM: "1", PFSemImpostos: 100.0, CNPJ: "12.345.678/0001-90", Laboratorio: "LAB A", ClasseTerapeutica: "N2B - ANALGÉSICOS"},
+			{CodigoGGREM: "3", PFSemImpostos: 50.0, CNPJ: "98.765.432/0001-10", Laboratorio: "LAB B", ClasseTerapeutica: "M1A - ANTI-REUMÁTICOS"},
+			{CodigoGGREM: "4", PFSemImpostos: nil},
+		},
+	}
+	atual := Output{
+		Metadados: Metadados{Data: "2025-04-01"},
+		Medicamentos: []Medicamento{
+			{CodigoGGREM: "1", PFSemImpostos: 104.0, CNPJ: "12.345.678/0001-90", Laboratorio: "LAB A", ClasseTerapeutica: "N2B - ANALGÉSICOS"},
+			{CodigoGGREM: "2", PFSemImpostos: 212.0, CNPJ: "12.345.678/0001-90", Laboratorio: "LAB A", ClasseTerapeutica: "M1A - ANTI-REUMÁTICOS"},
+			{CodigoGGREM: "3", PFSemImpostos: 49.0, CNPJ: "98.765.432/0001-10", Laboratorio: "LAB B", ClasseTerapeutica: "M1A - ANTI-REUMÁTICOS"},
+			{CodigoGGREM: "4", PFSemImpostos: 10.0},
+			{CodigoGGREM: "5", PFSemImpostos: 10.0},
+		},
+	}
+
+	relatorio := calculaReajuste(anterior, atual, 5)
+
+	if relatorio.DataAnterior != "2024-04-01" || relatorio.DataAtual != "2025-04-01" {
+		t.Errorf("datas inesperadas: %s, %s", relatorio.DataAnterior, relatorio.DataAtual)
+	}
+
+	expectedProdutos := []struct {
+		ggrem       string
+		variacao    float64
+		acimaDoTeto bool
+		classe      string
+	}{
+		{"1", 4, false, "N2B"},
+		{"2", 6, true, "M1A"},
+		{"3", -2, false, "M1A"},
+	}
+	if len(relatorio.Produtos) != len(expectedProdutos) {
+		t.Fatalf("esperado %d produtos, obtido %d", len(expectedProdutos), len(relatorio.Produtos))
+	}
+	for i, expected := range expectedProdutos {
+		produto := relatorio.Produtos[i]
+		if produto.CodigoGGREM != expected.ggrem || !quaseIgual(produto.Variacao, expected.variacao) ||
+			produto.AcimaDoTeto != expected.acimaDoTeto || produto.Classe != expected.classe {
+			t.Errorf("esperado: %+v, obtido: %+v", expected, produto)
+		}
+	}
+
+	if len(relatorio.Laboratorios) != 2 {
+		t.Fatalf("esperado 2 laboratórios, obtido %d", len(relatorio.Laboratorios))
+	}
+	labA := relatorio.Laboratorios[0]
+	if labA.Codigo != "12.345.678/0001-90" || labA.Produtos != 2 || !quaseIgual(labA.VariacaoMedia, 5) ||
+		!quaseIgual(labA.VariacaoMinima, 4) || !quaseIgual(labA.VariacaoMaxima, 6) || labA.AcimaDoTeto != 1 {
+		t.Errorf("laboratório inesperado: %+v", labA)
+	}
+
+	if len(relatorio.Classes) != 2 {
+		t.Fatalf("esperado 2 classes, obtido %d", len(relatorio.Classes))
+	}
+	m1a := relatorio.Classes[0]
+	if m1a.Codigo != "M1A" || m1a.Descricao != "ANTI-REUMÁTICOS" || m1a.Produtos != 2 || !quaseIgual(m1a.VariacaoMedia, 2) {
+		t.Errorf("classe inesperada: %+v", m1a)
+	}
+}
+
+func TestWriteReajusteProdutosCSV(t *testing.T) {
+	var buf bytes.Buffer
+	produtos := []ReajusteProduto{
+		{CodigoGGREM: "1", Produto: "PRODUTO, COM VÍRGULA", PrecoAnterior: 100, PrecoAtual: 104.5, Variacao: 4.5},
+	}
+	if err := writeReajusteProdutosCSV(&buf, produtos); err != nil {
+		t.Fatalf("writeReajusteProdutosCSV failed: %v", err)
+	}
+
+	expected := "CÓDIGO GGREM,PRODUTO,APRESENTAÇÃO,CNPJ,LABORATÓRIO,CLASSE TERAPÊUTICA,PREÇO ANTERIOR,PREÇO ATUAL,VARIAÇÃO (%),ACIMA DO TETO\n" +
+		"1,\"PRODUTO, COM VÍRGULA\",,,,,100,104.5,4.5,false\n"
+	if buf.String() != expected {
+		t.Errorf("esperado: %q, obtido: %q", expected, buf.String())
+	}
+}
+
+func quaseIgual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
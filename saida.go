@@ -0,0 +1,114 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatoJSON = "json"
+	FormatoFHIR = "fhir"
+	FormatoXML  = "xml"
+)
+
+// formatoSaida is a file format the output can be written in.
+type formatoSaida struct {
+	// extensao is appended to the name of the input file, without its own
+	// extension, to name the output file.
+	extensao string
+	codifica func(w io.Writer, output Output, opcoes OpcoesSaida) error
+}
+
+// formatosSaida lists the formats accepted by --format.
+var formatosSaida = map[string]formatoSaida{
+	FormatoJSON: {".json", codificaJSON},
+	FormatoFHIR: {".fhir.json", codificaFHIR},
+	FormatoXML:  {".xml", codificaXML},
+}
+
+func nomesFormatosSaida() []string {
+	nomes := make([]string, 0, len(formatosSaida))
+	for nome := range formatosSaida {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	return nomes
+}
+
+func (o OpcoesSaida) formato() string {
+	if o.Formato == "" {
+		return FormatoJSON
+	}
+	return o.Formato
+}
+
+func codificaJSON(w io.Writer, output Output, _ OpcoesSaida) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
+
+// nomeSaida returns the input file name with the extension of the format.
+func nomeSaida(infilePath string, formato formatoSaida) string {
+	return strings.TrimSuffix(infilePath, filepath.Ext(infilePath)) + formato.extensao
+}
+
+func writeSaidaFile(output Output, infilePath string, formato formatoSaida, opcoes OpcoesSaida) error {
+	outfilePath := nomeSaida(infilePath, formato)
+	outFile, err := os.Create(outfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outFile.Close()
+
+	if err := formato.codifica(outFile, output, opcoes); err != nil {
+		return err
+	}
+
+	fmt.Printf("Arquivo %s criado!\n", outfilePath)
+	return nil
+}
+
+func writeSaidaZip(output Output, infilePath string, formato formatoSaida, opcoes OpcoesSaida) error {
+	outfilePath := strings.TrimSuffix(infilePath, filepath.Ext(infilePath)) + ".zip"
+
+	// Create a buffer to write our archive to.
+	buf := new(bytes.Buffer)
+
+	// Create a new zip archive.
+	zipWriter := zip.NewWriter(buf)
+
+	// Create a new file in the zip archive.
+	zipFile, err := zipWriter.Create(filepath.Base(nomeSaida(infilePath, formato)))
+	if err != nil {
+		return fmt.Errorf("failed to create zip file: %w", err)
+	}
+
+	// Write the encoded output to the file in the zip archive.
+	if err := formato.codifica(zipFile, output, opcoes); err != nil {
+		return err
+	}
+
+	// Make sure to check the error on Close.
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+
+	// Write the .zip file to disk.
+	if err := os.WriteFile(outfilePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write zip file: %w", err)
+	}
+
+	fmt.Printf("Arquivo %s criado!\n", outfilePath)
+	return nil
+}
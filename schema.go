@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+const schemaID = "https://github.com/elvisdiniz/cmed-parser/schema/output.schema.json"
+
+// versaoSchema is the version of the output format, recorded in the
+// metadata of every output. It must change whenever geraSchema changes.
+const versaoSchema = "1.5.0"
+
+// tipoJSON returns a schema accepting the given JSON types.
+func tipoJSON(tipos ...string) map[string]any {
+	if len(tipos) == 1 {
+		return map[string]any{"type": tipos[0]}
+	}
+	return map[string]any{"type": tipos}
+}
+
+func refSchema(nome string) map[string]any {
+	return map[string]any{"$ref": "#/$defs/" + nome}
+}
+
+func anulavel(schema map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{tipoJSON("null"), schema}}
+}
+
+func arrayDe(items map[string]any, tipos ...string) map[string]any {
+	schema := tipoJSON(tipos...)
+	schema["items"] = items
+	return schema
+}
+
+func mapaDe(valores map[string]any) map[string]any {
+	return map[string]any{"type": "object", "additionalProperties": valores}
+}
+
+func objeto(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func enumAnulavel(codigos []string) map[string]any {
+	valores := []any{nil}
+	for _, codigo := range codigos {
+		valores = append(valores, codigo)
+	}
+	return map[string]any{"enum": valores}
+}
+
+// schemaColuna returns the schema of a spreadsheet column, following the
+// conversions made by processaValorCelula. Empty cells are null.
+func schemaColuna(header string) map[string]any {
+	switch {
+	case ehColunaPreco(header):
+		return tipoJSON("number", "null")
+	case ehColunaBooleana(header):
+		return tipoJSON("boolean", "null")
+	}
+	return tipoJSON("string", "null")
+}
+
+// geraSchema describes the output format as a JSON Schema (draft 2020-12).
+func geraSchema() map[string]any {
+	// Every field of a medicine is optional, since the columns can be
+	// selected with --columns and null values omitted with --omit-null.
+	medicamento := map[string]any{}
+	precos := map[string]any{}
+	for _, header := range cabecalho {
+		medicamento[header] = schemaColuna(header)
+		if ehColunaPreco(header) {
+			precos[header] = tipoJSON("number")
+		}
+	}
+	derivados := map[string]any{
+		Substancias:             arrayDe(tipoJSON("string"), "array"),
+		Classificacao:           anulavel(refSchema("ClassificacaoTerapeutica")),
+		TipoProdutoCodigo:       enumAnulavel(codigosEnum(tiposProduto, TipoProdutoDesconhecido)),
+		RegimePrecoCodigo:       enumAnulavel(codigosEnum(regimesPreco, RegimeDesconhecido)),
+		TarjaCodigo:             enumAnulavel(codigosEnum(tarjas, TarjaDesconhecida)),
+		ListaPISCOFINSCodigo:    enumAnulavel(codigosEnum(listasPISCOFINS, ListaDesconhecida)),
+		PrecosMarcados:          arrayDe(tipoJSON("string"), "array"),
+		ApresentacaoID:          tipoJSON("integer", "null"),
+		ApresentacaoDetalhes:    anulavel(refSchema("DetalhesApresentacao")),
+		GrupoEquivalenciaID:     tipoJSON("integer", "null"),
+		PrecosUnitarios:         anulavel(objeto(precos)),
+		QuantidadeIndeterminada: tipoJSON("boolean"),
+		Linha:                   tipoJSON("integer"),
+	}
+	for _, campo := range camposDerivados {
+		medicamento[campo] = derivados[campo]
+	}
+
+	contagemPorLista := mapaDe(tipoJSON("integer"))
+
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     schemaID,
+		"title":   "Tabela de preços de medicamentos da CMED",
+		"type":    "object",
+		"properties": map[string]any{
+			"metadados":           refSchema("Metadados"),
+			"medicamentos":        arrayDe(refSchema("Medicamento"), "array", "null"),
+			"laboratorios":        mapaDe(refSchema("CadastroLaboratorio")),
+			"gruposLaboratorios":  mapaDe(arrayDe(tipoJSON("string"), "array")),
+			"apresentacoes":       arrayDe(refSchema("ApresentacaoCatalogo"), "array", "null"),
+			"substancias":         mapaDe(arrayDe(tipoJSON("string"), "array")),
+			"classesTerapeuticas": mapaDe(tipoJSON("string")),
+			"resumoTributario":    refSchema("ResumoTributario"),
+			"gruposEquivalencia":  arrayDe(refSchema("GrupoEquivalencia"), "array", "null"),
+			"avisos":              arrayDe(tipoJSON("string"), "array"),
+		},
+		"required": []string{
+			"metadados", "medicamentos", "laboratorios", "gruposLaboratorios", "apresentacoes",
+			"substancias", "classesTerapeuticas", "resumoTributario", "gruposEquivalencia",
+		},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"Metadados": objeto(map[string]any{
+				"data":             tipoJSON("string"),
+				"data-atualizacao": tipoJSON("string"),
+				"observacoes":      arrayDe(tipoJSON("string"), "array", "null"),
+				"versao-schema":    tipoJSON("string"),
+				"versao-parser":    tipoJSON("string"),
+				"arquivo":          tipoJSON("string"),
+				"sha256":           tipoJSON("string"),
+				"planilha":         tipoJSON("string"),
+				"linha-cabecalho":  tipoJSON("integer"),
+				"linhas":           tipoJSON("integer"),
+				"gerado-em":        tipoJSON("string"),
+				"canonico":         tipoJSON("boolean"),
+				"colunas":          arrayDe(tipoJSON("string"), "array"),
+				"omite-nulos":      tipoJSON("boolean"),
+				"filtro":           tipoJSON("string"),
+			}, "data", "observacoes"),
+			"Medicamento": objeto(medicamento),
+			"ClassificacaoTerapeutica": objeto(map[string]any{
+				"codigo":    tipoJSON("string"),
+				"descricao": tipoJSON("string"),
+				"niveis":    arrayDe(tipoJSON("string"), "array"),
+			}, "codigo", "descricao", "niveis"),
+			"CadastroLaboratorio": objeto(map[string]any{
+				"nome":      tipoJSON("string"),
+				"variantes": arrayDe(tipoJSON("string"), "array"),
+				"produtos":  tipoJSON("integer"),
+			}, "nome", "variantes", "produtos"),
+			"ApresentacaoCatalogo": objeto(map[string]any{
+				"id":           tipoJSON("integer"),
+				"descricao":    tipoJSON("string"),
+				"ocorrencias":  tipoJSON("integer"),
+				"variantes":    arrayDe(tipoJSON("string"), "array"),
+				"codigosGgrem": arrayDe(tipoJSON("string"), "array"),
+			}, "id", "descricao", "ocorrencias", "variantes", "codigosGgrem"),
+			"ResumoTributario": objeto(map[string]any{
+				"porLista":       contagemPorLista,
+				"porLaboratorio": mapaDe(contagemPorLista),
+			}, "porLista", "porLaboratorio"),
+			"DetalhesApresentacao": objeto(map[string]any{
+				"concentracao": tipoJSON("string"),
+				"forma":        tipoJSON("string"),
+				"quantidade":   tipoJSON("number", "null"),
+				"unidade":      map[string]any{"enum": []any{"", "UN", "ML", "G"}},
+			}, "concentracao", "forma", "quantidade", "unidade"),
+			"MembroEquivalencia": objeto(map[string]any{
+				"codigoGgrem":      tipoJSON("string"),
+				"produto":          tipoJSON("string"),
+				"laboratorio":      tipoJSON("string"),
+				"apresentacao":     tipoJSON("string"),
+				"papel":            map[string]any{"enum": []any{PapelReferencia, PapelGenerico, PapelSimilar, PapelOutro}},
+				"precoSemImpostos": tipoJSON("number", "null"),
+				"precoUnitario":    tipoJSON("number", "null"),
+			}, "codigoGgrem", "produto", "laboratorio", "apresentacao", "papel", "precoSemImpostos", "precoUnitario"),
+			"GrupoEquivalencia": objeto(map[string]any{
+				"id":                  tipoJSON("integer"),
+				"substancias":         arrayDe(tipoJSON("string"), "array"),
+				"concentracao":        tipoJSON("string"),
+				"forma":               tipoJSON("string"),
+				"membros":             arrayDe(refSchema("MembroEquivalencia"), "array"),
+				"maisBarato":          tipoJSON("string", "null"),
+				"maisCaro":            tipoJSON("string", "null"),
+				"precoUnitarioMinimo": tipoJSON("number", "null"),
+				"precoUnitarioMaximo": tipoJSON("number", "null"),
+				"amplitudePercentual": tipoJSON("number", "null"),
+			}, "id", "substancias", "concentracao", "forma", "membros", "maisBarato", "maisCaro", "precoUnitarioMinimo", "precoUnitarioMaximo", "amplitudePercentual"),
+		},
+	}
+}
+
+func runSchema(args []string) {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	formato := flags.String("format", FormatoJSON, "Formato descrito: json (JSON Schema) ou xml (XML Schema)")
+	flags.Parse(args)
+
+	switch *formato {
+	case FormatoXML:
+		fmt.Print(geraXSD())
+		return
+	case FormatoJSON:
+	default:
+		log.Fatalf("formato desconhecido: %s. Use json ou xml", *formato)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(geraSchema()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validaSchema checks a decoded JSON value against the subset of JSON Schema
+// used by geraSchema: type, enum, properties, required,
+// additionalProperties, items, anyOf and local $ref.
+func validaSchema(raiz, schema map[string]any, valor any, caminho string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		defs := raiz["$defs"].(map[string]any)
+		return validaSchema(raiz, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), valor, caminho)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		for _, alternativa := range anyOf {
+			if len(validaSchema(raiz, alternativa.(map[string]any), valor, caminho)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: nenhuma alternativa de anyOf aceita %v", caminho, valor)}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.Contains(enum, valor) {
+			return []string{fmt.Sprintf("%s: valor %v fora do enum", caminho, valor)}
+		}
+	}
+
+	if tipos, ok := schema["type"]; ok {
+		var aceitos []any
+		if tipo, ok := tipos.(string); ok {
+			aceitos = []any{tipo}
+		} else {
+			aceitos = tipos.([]any)
+		}
+		if !slices.ContainsFunc(aceitos, func(tipo any) bool { return tipoAceita(tipo.(string), valor) }) {
+			return []string{fmt.Sprintf("%s: tipo de %v não é %v", caminho, valor, aceitos)}
+		}
+	}
+
+	var erros []string
+	switch v := valor.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, campo := range required {
+				if _, ok := v[campo.(string)]; !ok {
+					erros = append(erros, fmt.Sprintf("%s: campo obrigatório %s ausente", caminho, campo))
+				}
+			}
+		}
+		for campo, valorCampo := range v {
+			if propriedade, ok := properties[campo]; ok {
+				erros = append(erros, validaSchema(raiz, propriedade.(map[string]any), valorCampo, caminho+"/"+campo)...)
+				continue
+			}
+			switch adicionais := schema["additionalProperties"].(type) {
+			case bool:
+				if !adicionais {
+					erros = append(erros, fmt.Sprintf("%s: campo não permitido %s", caminho, campo))
+				}
+			case map[string]any:
+				erros = append(erros, validaSchema(raiz, adicionais, valorCampo, caminho+"/"+campo)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				erros = append(erros, validaSchema(raiz, items, item, fmt.Sprintf("%s/%d", caminho, i))...)
+			}
+		}
+	}
+	return erros
+}
+
+func tipoAceita(tipo string, valor any) bool {
+	switch tipo {
+	case "null":
+		return valor == nil
+	case "boolean":
+		_, ok := valor.(bool)
+		return ok
+	case "string":
+		_, ok := valor.(string)
+		return ok
+	case "number":
+		_, ok := valor.(float64)
+		return ok
+	case "integer":
+		n, ok := valor.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := valor.([]any)
+		return ok
+	case "object":
+		_, ok := valor.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func schemaDecodificado(t *testing.T) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(geraSchema())
+	if err != nil {
+		t.Fatalf("failed to encode schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("failed to decode schema: %v", err)
+	}
+	return schema
+}
+
+func TestSchemaArquivo(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(geraSchema()); err != nil {
+		t.Fatalf("failed to encode schema: %v", err)
+	}
+
+	arquivo, err := os.ReadFile("schema/output.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read schema file: %v", err)
+	}
+	if !bytes.Equal(arquivo, buf.Bytes()) {
+		t.Error("schema/output.schema.json desatualizado; gere-o novamente com: go run . schema > schema/output.schema.json")
+	}
+}
+
+func TestOutputValidaSchema(t *testing.T) {
+	schema := schemaDecodificado(t)
+
+	rows := linhasSinteticas(20)
+	rows[2][0] = ""      // medicine without substance
+	rows[3][10] = "-"    // medicine without therapeutic class
+	rows[4][11] = "Novo" // known product type
+	rows[5][11] = "Inédito"
+	rows[6][13] = "12,50*"
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(rows, data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+
+	jsonData, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("failed to encode json: %v", err)
+	}
+	var documento any
+	if err := json.Unmarshal(jsonData, &documento); err != nil {
+		t.Fatalf("failed to decode json: %v", err)
+	}
+
+	for _, erro := range validaSchema(schema, schema, documento, "") {
+		t.Error(erro)
+	}
+}
+
+func TestSchemaRejeitaOutputInvalido(t *testing.T) {
+	schema := schemaDecodificado(t)
+
+	var documento map[string]any
+	invalido := `{
+		"metadados": {"data": "2025-07-03", "observacoes": null},
+		"medicamentos": [{"PF 18%": "caro", "PRECO": 1}],
+		"laboratorios": {}, "gruposLaboratorios": {}, "apresentacoes": null,
+		"substancias": {}, "classesTerapeuticas": {}, "resumoTributario": {"porLista": {}, "porLaboratorio": {}},
+		"extra": true
+	}`
+	if err := json.Unmarshal([]byte(invalido), &documento); err != nil {
+		t.Fatalf("failed to decode json: %v", err)
+	}
+
+	erros := validaSchema(schema, schema, documento, "")
+	if len(erros) == 0 {
+		t.Fatal("esperado erros de validação")
+	}
+	for _, esperado := range []string{"campo não permitido extra", "/medicamentos/0/PF 18%: tipo", "campo não permitido PRECO"} {
+		if !slices.ContainsFunc(erros, func(erro string) bool { return strings.Contains(erro, esperado) }) {
+			t.Errorf("esperado erro contendo %q, obtido: %v", esperado, erros)
+		}
+	}
+}
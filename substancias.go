@@ -0,0 +1,32 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var separadorSubstancias = regexp.MustCompile(`[;+]`)
+
+// normalizaTexto returns the canonical form of free text such as an active
+// ingredient or a laboratory name: trimmed, upper case, without accents and
+// with single spaces.
+func normalizaTexto(s string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(removeAccents(s))), " ")
+}
+
+// separaSubstancias splits a SUBSTÂNCIA cell of a combination product into
+// its normalized ingredients, keeping the original order and skipping
+// duplicates.
+func separaSubstancias(s string) []string {
+	substancias := []string{}
+	vistas := make(map[string]bool)
+	for _, parte := range separadorSubstancias.Split(s, -1) {
+		substancia := normalizaTexto(parte)
+		if substancia == "" || vistas[substancia] {
+			continue
+		}
+		vistas[substancia] = true
+		substancias = append(substancias, substancia)
+	}
+	return substancias
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizaSubstancia(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"já normalizada", "IBUPROFENO", "IBUPROFENO"},
+		{"minúsculas e acentos", "cloridrato de metformina ", "CLORIDRATO DE METFORMINA"},
+		{"acentos", "ÁCIDO ACETILSALICÍLICO", "ACIDO ACETILSALICILICO"},
+		{"espaços repetidos", "  DIPIRONA   SÓDICA ", "DIPIRONA SODICA"},
+		{"string vazia", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := normalizaTexto(tc.input)
+			if result != tc.expected {
+				t.Errorf("esperado: %s, obtido: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSeparaSubstancias(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"substância única", "IBUPROFENO", []string{"IBUPROFENO"}},
+		{"ponto e vírgula", "LOSARTANA POTÁSSICA;HIDROCLOROTIAZIDA", []string{"LOSARTANA POTASSICA", "HIDROCLOROTIAZIDA"}},
+		{"sinal de mais", "amoxicilina + clavulanato de potássio", []string{"AMOXICILINA", "CLAVULANATO DE POTASSIO"}},
+		{"separadores mistos", "DIPIRONA; CAFEÍNA + ORFENADRINA", []string{"DIPIRONA", "CAFEINA", "ORFENADRINA"}},
+		{"duplicadas", "CAFEÍNA;CAFEINA", []string{"CAFEINA"}},
+		{"partes vazias", "PARACETAMOL;; ", []string{"PARACETAMOL"}},
+		{"string vazia", "", []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := separaSubstancias(tc.input)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type TipoProduto string
+
+const (
+	TipoProdutoNovo         TipoProduto = "NOVO"
+	TipoProdutoGenerico     TipoProduto = "GENERICO"
+	TipoProdutoSimilar      TipoProduto = "SIMILAR"
+	TipoProdutoBiologico    TipoProduto = "BIOLOGICO"
+	TipoProdutoEspecifico   TipoProduto = "ESPECIFICO"
+	TipoProdutoFitoterapico TipoProduto = "FITOTERAPICO"
+	TipoProdutoRadiofarmaco TipoProduto = "RADIOFARMACO"
+	TipoProdutoDesconhecido TipoProduto = "DESCONHECIDO"
+)
+
+type TipoRegime string
+
+const (
+	RegimeRegulado     TipoRegime = "REGULADO"
+	RegimeLiberado     TipoRegime = "LIBERADO"
+	RegimeDesconhecido TipoRegime = "DESCONHECIDO"
+)
+
+type TipoTarja string
+
+const (
+	TarjaVermelha             TipoTarja = "VERMELHA"
+	TarjaVermelhaSobRestricao TipoTarja = "VERMELHA_SOB_RESTRICAO"
+	TarjaPreta                TipoTarja = "PRETA"
+	TarjaVendaLivre           TipoTarja = "VENDA_LIVRE"
+	TarjaDesconhecida         TipoTarja = "DESCONHECIDA"
+)
+
+type ListaPISCOFINS string
+
+const (
+	ListaPositiva     ListaPISCOFINS = "POSITIVA"
+	ListaNegativa     ListaPISCOFINS = "NEGATIVA"
+	ListaNeutra       ListaPISCOFINS = "NEUTRA"
+	ListaDesconhecida ListaPISCOFINS = "DESCONHECIDA"
+)
+
+// The keys of the mapping tables are normalized with chaveEnum, so spelling
+// variants that differ only in accents, case, spacing or punctuation share
+// the same entry.
+var tiposProduto = map[string]TipoProduto{
+	"NOVO":             TipoProdutoNovo,
+	"PRODUTONOVO":      TipoProdutoNovo,
+	"GENERICO":         TipoProdutoGenerico,
+	"GENERICOS":        TipoProdutoGenerico,
+	"SIMILAR":          TipoProdutoSimilar,
+	"SIMILARES":        TipoProdutoSimilar,
+	"BIOLOGICO":        TipoProdutoBiologico,
+	"BIOLOGICOS":       TipoProdutoBiologico,
+	"PRODUTOBIOLOGICO": TipoProdutoBiologico,
+	"ESPECIFICO":       TipoProdutoEspecifico,
+	"ESPECIFICOS":      TipoProdutoEspecifico,
+	"FITOTERAPICO":     TipoProdutoFitoterapico,
+	"FITOTERAPICOS":    TipoProdutoFitoterapico,
+	"RADIOFARMACO":     TipoProdutoRadiofarmaco,
+	"RADIOFARMACOS":    TipoProdutoRadiofarmaco,
+}
+
+var regimesPreco = map[string]TipoRegime{
+	"REGULADO": RegimeRegulado,
+	"LIBERADO": RegimeLiberado,
+}
+
+var tarjas = map[string]TipoTarja{
+	"TARJAVERMELHA":             TarjaVermelha,
+	"VERMELHA":                  TarjaVermelha,
+	"TARJAVERMELHASOBRESTRICAO": TarjaVermelhaSobRestricao,
+	"VERMELHASOBRESTRICAO":      TarjaVermelhaSobRestricao,
+	"TARJAPRETA":                TarjaPreta,
+	"PRETA":                     TarjaPreta,
+	"VENDALIVRE":                TarjaVendaLivre,
+	"SEMTARJA":                  TarjaVendaLivre,
+	"ISENTODEPRESCRICAO":        TarjaVendaLivre,
+	"ISENTODEPRESCRICAOMEDICA":  TarjaVendaLivre,
+}
+
+var listasPISCOFINS = map[string]ListaPISCOFINS{
+	"POSITIVA":      ListaPositiva,
+	"LISTAPOSITIVA": ListaPositiva,
+	"NEGATIVA":      ListaNegativa,
+	"LISTANEGATIVA": ListaNegativa,
+	"NEUTRA":        ListaNeutra,
+	"LISTANEUTRA":   ListaNeutra,
+}
+
+// chaveEnum normalizes a cell value for lookup in the mapping tables,
+// keeping only letters and digits without accents, in upper case.
+func chaveEnum(valor string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, removeAccents(valor))
+}
+
+// classificaValor maps a cell value to its canonical code. The boolean is
+// false when the value is not present in the mapping table.
+func classificaValor[T ~string](mapa map[string]T, valor string) (T, bool) {
+	codigo, ok := mapa[chaveEnum(valor)]
+	return codigo, ok
+}
+
+// codigoEnum returns the canonical code for a cell value, or nil when the
+// cell is empty. Values missing from the mapping table are reported as
+// desconhecido with a false boolean, so the caller can warn about new
+// categories.
+func codigoEnum[T ~string](valor any, mapa map[string]T, desconhecido T) (any, bool) {
+	s, ok := valor.(string)
+	if !ok {
+		return nil, true
+	}
+	if codigo, ok := classificaValor(mapa, s); ok {
+		return codigo, true
+	}
+	return desconhecido, false
+}
+
+// codigosEnum lists the canonical codes of a mapping table, in sorted order,
+// followed by the code used for unknown values.
+func codigosEnum[T ~string](mapa map[string]T, desconhecido T) []string {
+	vistos := make(map[T]bool)
+	var codigos []string
+	for _, codigo := range mapa {
+		if !vistos[codigo] {
+			vistos[codigo] = true
+			codigos = append(codigos, string(codigo))
+		}
+	}
+	slices.Sort(codigos)
+	return append(codigos, string(desconhecido))
+}
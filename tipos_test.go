@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestChaveEnum(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"simples", "Novo", "NOVO"},
+		{"acentos", "Genérico", "GENERICO"},
+		{"espaços", "Tarja  Vermelha", "TARJAVERMELHA"},
+		{"pontuação", "Tarja Vermelha (*)", "TARJAVERMELHA"},
+		{"string vazia", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := chaveEnum(tc.input)
+			if result != tc.expected {
+				t.Errorf("esperado: %s, obtido: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCodigoEnum(t *testing.T) {
+	testCases := []struct {
+		name       string
+		value      any
+		classifica func(any) (any, bool)
+		expected   any
+		ok         bool
+	}{
+		{"tipo genérico", "Genérico", classificaTipoProduto, TipoProdutoGenerico, true},
+		{"tipo biológico plural", "Biológicos", classificaTipoProduto, TipoProdutoBiologico, true},
+		{"tipo similar maiúsculo", "SIMILAR", classificaTipoProduto, TipoProdutoSimilar, true},
+		{"tipo desconhecido", "Inédito", classificaTipoProduto, TipoProdutoDesconhecido, false},
+		{"tipo vazio", nil, classificaTipoProduto, nil, true},
+		{"regime regulado", "Regulado", classificaRegimePreco, RegimeRegulado, true},
+		{"regime liberado", "liberado", classificaRegimePreco, RegimeLiberado, true},
+		{"regime desconhecido", "Monitorado", classificaRegimePreco, RegimeDesconhecido, false},
+		{"classificaTarja vermelha", "Tarja Vermelha", classificaTarja, TarjaVermelha, true},
+		{"classificaTarja vermelha com asterisco", "Tarja Vermelha(*)", classificaTarja, TarjaVermelha, true},
+		{"classificaTarja sob restrição", "Tarja Vermelha sob restrição", classificaTarja, TarjaVermelhaSobRestricao, true},
+		{"classificaTarja preta", "Tarja Preta", classificaTarja, TarjaPreta, true},
+		{"venda livre", "Venda Livre", classificaTarja, TarjaVendaLivre, true},
+		{"classificaTarja desconhecida", "Tarja Azul", classificaTarja, TarjaDesconhecida, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := tc.classifica(tc.value)
+			if ok != tc.ok {
+				t.Fatalf("esperado ok: %v, obtido: %v", tc.ok, ok)
+			}
+			if result != tc.expected {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func classificaTipoProduto(v any) (any, bool) {
+	return codigoEnum(v, tiposProduto, TipoProdutoDesconhecido)
+}
+
+func classificaRegimePreco(v any) (any, bool) {
+	return codigoEnum(v, regimesPreco, RegimeDesconhecido)
+}
+
+func classificaTarja(v any) (any, bool) {
+	return codigoEnum(v, tarjas, TarjaDesconhecida)
+}
+
+func classificaListaPISCOFINS(v any) (any, bool) {
+	return codigoEnum(v, listasPISCOFINS, ListaDesconhecida)
+}
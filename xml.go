@@ -0,0 +1,577 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// xmlNamespace is the target namespace of schema/output.xsd.
+const xmlNamespace = "https://github.com/elvisdiniz/cmed-parser/schema/output.xsd"
+
+// camposForaXML are the derived fields not written as elements: the marked
+// and unit prices become attributes of each price, and the row an attribute
+// of the medicine.
+var camposForaXML = map[string]bool{
+	PrecosMarcados:  true,
+	PrecosUnitarios: true,
+	Linha:           true,
+}
+
+// itensXML names the items of the list fields.
+var itensXML = map[string]string{
+	Substancias: "Substancia",
+}
+
+// precoXML is a price column of a medicine, such as PF 17,5% ALC.
+type precoXML struct {
+	Coluna string `xml:"coluna,attr"`
+	Tipo   string `xml:"tipo,attr"`
+	// ICMS is the rate in percent, absent for the prices without taxes.
+	ICMS     string `xml:"icms,attr,omitempty"`
+	ALC      bool   `xml:"alc,attr"`
+	Valor    string `xml:"valor,attr"`
+	Unitario string `xml:"unitario,attr,omitempty"`
+	Marcado  bool   `xml:"marcado,attr,omitempty"`
+}
+
+type precosXML struct {
+	Precos []precoXML `xml:"Preco"`
+}
+
+// The catalogs kept in maps are written as lists sorted by key, with the
+// key as an attribute of each item.
+type laboratorioXML struct {
+	CNPJ string `xml:"cnpj,attr"`
+	CadastroLaboratorio
+}
+
+type grupoLaboratoriosXML struct {
+	Raiz  string   `xml:"raiz,attr"`
+	CNPJs []string `xml:"CNPJ"`
+}
+
+type substanciaXML struct {
+	Nome         string   `xml:"nome,attr"`
+	CodigosGGREM []string `xml:"CodigoGGREM"`
+}
+
+type classeTerapeuticaXML struct {
+	Codigo    string `xml:"codigo,attr"`
+	Descricao string `xml:",chardata"`
+}
+
+type contagemListaXML struct {
+	Lista        ListaPISCOFINS `xml:"codigo,attr"`
+	Medicamentos int            `xml:",chardata"`
+}
+
+type resumoLaboratorioXML struct {
+	CNPJ   string             `xml:"cnpj,attr"`
+	Listas []contagemListaXML `xml:"Lista"`
+}
+
+type resumoTributarioXML struct {
+	PorLista       []contagemListaXML     `xml:"PorLista>Lista"`
+	PorLaboratorio []resumoLaboratorioXML `xml:"PorLaboratorio>Laboratorio"`
+}
+
+func chavesOrdenadas[K cmp.Ordered, V any](m map[K]V) []K {
+	chaves := make([]K, 0, len(m))
+	for chave := range m {
+		chaves = append(chaves, chave)
+	}
+	slices.Sort(chaves)
+	return chaves
+}
+
+func contagensListaXML(contagens map[ListaPISCOFINS]int) []contagemListaXML {
+	var itens []contagemListaXML
+	for _, lista := range chavesOrdenadas(contagens) {
+		itens = append(itens, contagemListaXML{lista, contagens[lista]})
+	}
+	return itens
+}
+
+// catalogosXML returns the catalogs of the output, in the order of the JSON
+// output, as the elements that hold them.
+func catalogosXML(output Output) []struct {
+	nome  string
+	valor any
+} {
+	laboratorios := struct {
+		Itens []laboratorioXML `xml:"Laboratorio"`
+	}{}
+	for _, cnpj := range chavesOrdenadas(output.Laboratorios) {
+		laboratorios.Itens = append(laboratorios.Itens, laboratorioXML{cnpj, output.Laboratorios[cnpj]})
+	}
+
+	grupos := struct {
+		Itens []grupoLaboratoriosXML `xml:"GrupoLaboratorios"`
+	}{}
+	for _, raiz := range chavesOrdenadas(output.GruposLaboratorios) {
+		grupos.Itens = append(grupos.Itens, grupoLaboratoriosXML{raiz, output.GruposLaboratorios[raiz]})
+	}
+
+	substancias := struct {
+		Itens []substanciaXML `xml:"Substancia"`
+	}{}
+	for _, nome := range chavesOrdenadas(output.Substancias) {
+		substancias.Itens = append(substancias.Itens, substanciaXML{nome, output.Substancias[nome]})
+	}
+
+	classes := struct {
+		Itens []classeTerapeuticaXML `xml:"ClasseTerapeutica"`
+	}{}
+	for _, codigo := range chavesOrdenadas(output.ClassesTerapeuticas) {
+		classes.Itens = append(classes.Itens, classeTerapeuticaXML{codigo, output.ClassesTerapeuticas[codigo]})
+	}
+
+	resumo := resumoTributarioXML{PorLista: contagensListaXML(output.ResumoTributario.PorLista)}
+	for _, cnpj := range chavesOrdenadas(output.ResumoTributario.PorLaboratorio) {
+		resumo.PorLaboratorio = append(resumo.PorLaboratorio, resumoLaboratorioXML{cnpj, contagensListaXML(output.ResumoTributario.PorLaboratorio[cnpj])})
+	}
+
+	return []struct {
+		nome  string
+		valor any
+	}{
+		{"Laboratorios", laboratorios},
+		{"GruposLaboratorios", grupos},
+		{"Apresentacoes", struct {
+			Itens []ApresentacaoCatalogo `xml:"Apresentacao"`
+		}{output.Apresentacoes}},
+		{"Substancias", substancias},
+		{"ClassesTerapeuticas", classes},
+		{"ResumoTributario", resumo},
+		{"GruposEquivalencia", struct {
+			Itens []GrupoEquivalencia `xml:"GrupoEquivalencia"`
+		}{output.GruposEquivalencia}},
+	}
+}
+
+// nomesXML maps the keys of a medicine to the names of their constants,
+// which are the XML element names.
+func nomesXML() map[string]string {
+	nomes := make(map[string]string, len(colunasPorNome))
+	for nome, chave := range colunasPorNome {
+		nomes[chave] = nome
+	}
+	return nomes
+}
+
+func formataDecimal(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func elementoXML(nome string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: nome}}
+}
+
+// precosMedicamentoXML lists the prices of a medicine in column order.
+func precosMedicamentoXML(medicamento Medicamento, nomes map[string]string) []precoXML {
+	marcados, _ := medicamento[PrecosMarcados].([]string)
+	unitarios, _ := medicamento[PrecosUnitarios].(map[string]float64)
+
+	var precos []precoXML
+	for _, header := range cabecalho {
+		valor, ok := medicamento[header].(float64)
+		if !ok || !ehColunaPreco(header) {
+			continue
+		}
+		preco := precoXML{
+			Coluna:  nomes[header],
+			Tipo:    strings.Fields(header)[0],
+			Valor:   formataDecimal(valor),
+			Marcado: slices.Contains(marcados, header),
+		}
+		if _, aliquota, alc, ok := aliquotaColuna(header); ok {
+			preco.ICMS = formataDecimal(aliquota)
+			preco.ALC = alc
+		}
+		if unitario, ok := unitarios[header]; ok {
+			preco.Unitario = formataDecimal(unitario)
+		}
+		precos = append(precos, preco)
+	}
+	return precos
+}
+
+// escreveCampoXML writes a field of a medicine as an element named after
+// its constant. Null fields are left out.
+func escreveCampoXML(encoder *xml.Encoder, nomes map[string]string, campo string, valor any) error {
+	inicio := elementoXML(nomes[campo])
+	switch v := valor.(type) {
+	case nil:
+		return nil
+	case float64:
+		return encoder.EncodeElement(formataDecimal(v), inicio)
+	case []string:
+		if err := encoder.EncodeToken(inicio); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := encoder.EncodeElement(item, elementoXML(itensXML[campo])); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(inicio.End())
+	}
+	return encoder.EncodeElement(valor, inicio)
+}
+
+func escreveMedicamentoXML(encoder *xml.Encoder, medicamento Medicamento, nomes map[string]string) error {
+	inicio := elementoXML("Medicamento")
+	if linha, ok := medicamento[Linha].(int); ok {
+		inicio.Attr = append(inicio.Attr, xml.Attr{Name: xml.Name{Local: "linha"}, Value: strconv.Itoa(linha)})
+	}
+	if err := encoder.EncodeToken(inicio); err != nil {
+		return err
+	}
+
+	for _, header := range cabecalho {
+		if ehColunaPreco(header) {
+			continue
+		}
+		if err := escreveCampoXML(encoder, nomes, header, medicamento[header]); err != nil {
+			return err
+		}
+	}
+	if precos := precosMedicamentoXML(medicamento, nomes); len(precos) > 0 {
+		if err := encoder.EncodeElement(precosXML{precos}, elementoXML("Precos")); err != nil {
+			return err
+		}
+	}
+	for _, campo := range camposDerivados {
+		if camposForaXML[campo] {
+			continue
+		}
+		if err := escreveCampoXML(encoder, nomes, campo, medicamento[campo]); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(inicio.End())
+}
+
+// codificaXML writes the output as XML, described by schema/output.xsd.
+// Elements of the medicines are named after the column constants, and each
+// price is a Preco element with the ICMS rate as attributes. The medicines
+// are encoded one at a time, and the encoder flushes its buffer as it
+// fills, so the document is never held in memory; the catalogs follow
+// them.
+func codificaXML(w io.Writer, output Output, _ OpcoesSaida) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write xml: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	raiz := elementoXML("TabelaCMED")
+	raiz.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "xmlns"}, Value: xmlNamespace},
+		{Name: xml.Name{Local: "versaoSchema"}, Value: versaoSchema},
+	}
+	nomes := nomesXML()
+
+	err := func() error {
+		if err := encoder.EncodeToken(raiz); err != nil {
+			return err
+		}
+		if err := encoder.EncodeElement(output.Metadados, elementoXML("Metadados")); err != nil {
+			return err
+		}
+
+		medicamentos := elementoXML("Medicamentos")
+		if err := encoder.EncodeToken(medicamentos); err != nil {
+			return err
+		}
+		for _, medicamento := range output.Medicamentos {
+			if err := escreveMedicamentoXML(encoder, medicamento, nomes); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(medicamentos.End()); err != nil {
+			return err
+		}
+
+		for _, catalogo := range catalogosXML(output) {
+			if err := encoder.EncodeElement(catalogo.valor, elementoXML(catalogo.nome)); err != nil {
+				return err
+			}
+		}
+
+		if len(output.Avisos) > 0 {
+			avisos := struct {
+				Avisos []string `xml:"Aviso"`
+			}{output.Avisos}
+			if err := encoder.EncodeElement(avisos, elementoXML("Avisos")); err != nil {
+				return err
+			}
+		}
+
+		if err := encoder.EncodeToken(raiz.End()); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}()
+	if err != nil {
+		return fmt.Errorf("failed to encode xml: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// tiposXSD are the XSD types of the derived fields written as elements.
+var tiposXSD = map[string]string{
+	Substancias:             "Substancias",
+	Classificacao:           "ClassificacaoTerapeutica",
+	TipoProdutoCodigo:       "TipoProduto",
+	RegimePrecoCodigo:       "RegimePreco",
+	TarjaCodigo:             "Tarja",
+	ListaPISCOFINSCodigo:    "ListaPISCOFINS",
+	ApresentacaoID:          "xs:int",
+	ApresentacaoDetalhes:    "DetalhesApresentacao",
+	GrupoEquivalenciaID:     "xs:int",
+	QuantidadeIndeterminada: "xs:boolean",
+}
+
+// geraXSD describes the XML output format as an XML Schema, following the
+// same columns as geraSchema.
+func geraXSD() string {
+	nomes := nomesXML()
+	var b strings.Builder
+	linha := func(nivel int, formato string, args ...any) {
+		b.WriteString(strings.Repeat("  ", nivel))
+		fmt.Fprintf(&b, formato, args...)
+		b.WriteString("\n")
+	}
+	lista := func(nome, item, tipo, minimo string) {
+		linha(1, `<xs:complexType name="%s">`, nome)
+		linha(2, `<xs:sequence>`)
+		linha(3, `<xs:element name="%s" type="%s" minOccurs="%s" maxOccurs="unbounded"/>`, item, tipo, minimo)
+		linha(2, `</xs:sequence>`)
+		linha(1, `</xs:complexType>`)
+	}
+	enumeracao := func(nome string, valores []string) {
+		linha(1, `<xs:simpleType name="%s">`, nome)
+		linha(2, `<xs:restriction base="xs:string">`)
+		for _, valor := range valores {
+			linha(3, `<xs:enumeration value="%s"/>`, valor)
+		}
+		linha(2, `</xs:restriction>`)
+		linha(1, `</xs:simpleType>`)
+	}
+
+	linha(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	linha(0, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="%s" xmlns="%s" elementFormDefault="qualified">`, xmlNamespace, xmlNamespace)
+
+	linha(1, `<xs:element name="TabelaCMED">`)
+	linha(2, `<xs:complexType>`)
+	linha(3, `<xs:sequence>`)
+	linha(4, `<xs:element name="Metadados" type="Metadados"/>`)
+	linha(4, `<xs:element name="Medicamentos" type="Medicamentos"/>`)
+	linha(4, `<xs:element name="Laboratorios" type="Laboratorios"/>`)
+	linha(4, `<xs:element name="GruposLaboratorios" type="GruposLaboratorios"/>`)
+	linha(4, `<xs:element name="Apresentacoes" type="Apresentacoes"/>`)
+	linha(4, `<xs:element name="Substancias" type="CatalogoSubstancias"/>`)
+	linha(4, `<xs:element name="ClassesTerapeuticas" type="ClassesTerapeuticas"/>`)
+	linha(4, `<xs:element name="ResumoTributario" type="ResumoTributario"/>`)
+	linha(4, `<xs:element name="GruposEquivalencia" type="GruposEquivalencia"/>`)
+	linha(4, `<xs:element name="Avisos" type="Avisos" minOccurs="0"/>`)
+	linha(3, `</xs:sequence>`)
+	linha(3, `<xs:attribute name="versaoSchema" type="xs:string" use="required"/>`)
+	linha(2, `</xs:complexType>`)
+	linha(1, `</xs:element>`)
+
+	linha(1, `<xs:complexType name="Metadados">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Data" type="xs:string"/>`)
+	linha(3, `<xs:element name="DataAtualizacao" type="xs:string" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Observacoes" type="Observacoes" minOccurs="0"/>`)
+	for _, campo := range []string{"VersaoSchema", "VersaoParser", "Arquivo", "SHA256", "Planilha"} {
+		linha(3, `<xs:element name="%s" type="xs:string" minOccurs="0"/>`, campo)
+	}
+	linha(3, `<xs:element name="LinhaCabecalho" type="xs:int" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Linhas" type="xs:int" minOccurs="0"/>`)
+	linha(3, `<xs:element name="GeradoEm" type="xs:string" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Canonico" type="xs:boolean" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Colunas" type="Colunas" minOccurs="0"/>`)
+	linha(3, `<xs:element name="OmiteNulos" type="xs:boolean" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Filtro" type="xs:string" minOccurs="0"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(1, `</xs:complexType>`)
+	lista("Observacoes", "Observacao", "xs:string", "0")
+	lista("Colunas", "Coluna", "xs:string", "0")
+	lista("Medicamentos", "Medicamento", "Medicamento", "0")
+	lista("Avisos", "Aviso", "xs:string", "0")
+
+	// Every element of a medicine is optional, since null fields are left
+	// out and the columns can be selected with --columns.
+	linha(1, `<xs:complexType name="Medicamento">`)
+	linha(2, `<xs:sequence>`)
+	var colunasPreco []string
+	for _, header := range cabecalho {
+		if ehColunaPreco(header) {
+			colunasPreco = append(colunasPreco, nomes[header])
+			continue
+		}
+		tipo := "xs:string"
+		if ehColunaBooleana(header) {
+			tipo = "xs:boolean"
+		}
+		linha(3, `<xs:element name="%s" type="%s" minOccurs="0"/>`, nomes[header], tipo)
+	}
+	linha(3, `<xs:element name="Precos" type="Precos" minOccurs="0"/>`)
+	for _, campo := range camposDerivados {
+		if !camposForaXML[campo] {
+			linha(3, `<xs:element name="%s" type="%s" minOccurs="0"/>`, nomes[campo], tiposXSD[campo])
+		}
+	}
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="linha" type="xs:int"/>`)
+	linha(1, `</xs:complexType>`)
+
+	lista("Precos", "Preco", "Preco", "1")
+	linha(1, `<xs:complexType name="Preco">`)
+	linha(2, `<xs:attribute name="coluna" type="ColunaPreco" use="required"/>`)
+	linha(2, `<xs:attribute name="tipo" type="TipoPreco" use="required"/>`)
+	linha(2, `<xs:attribute name="icms" type="xs:decimal"/>`)
+	linha(2, `<xs:attribute name="alc" type="xs:boolean" use="required"/>`)
+	linha(2, `<xs:attribute name="valor" type="xs:decimal" use="required"/>`)
+	linha(2, `<xs:attribute name="unitario" type="xs:decimal"/>`)
+	linha(2, `<xs:attribute name="marcado" type="xs:boolean" default="false"/>`)
+	linha(1, `</xs:complexType>`)
+	enumeracao("ColunaPreco", colunasPreco)
+	enumeracao("TipoPreco", []string{"PF", "PMVG"})
+
+	lista("Substancias", "Substancia", "xs:string", "0")
+	linha(1, `<xs:complexType name="ClassificacaoTerapeutica">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Codigo" type="xs:string"/>`)
+	linha(3, `<xs:element name="Descricao" type="xs:string"/>`)
+	linha(3, `<xs:element name="Niveis" type="Niveis"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(1, `</xs:complexType>`)
+	lista("Niveis", "Nivel", "xs:string", "1")
+	enumeracao("TipoProduto", codigosEnum(tiposProduto, TipoProdutoDesconhecido))
+	enumeracao("RegimePreco", codigosEnum(regimesPreco, RegimeDesconhecido))
+	enumeracao("Tarja", codigosEnum(tarjas, TarjaDesconhecida))
+	enumeracao("ListaPISCOFINS", codigosEnum(listasPISCOFINS, ListaDesconhecida))
+	linha(1, `<xs:complexType name="DetalhesApresentacao">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Concentracao" type="xs:string"/>`)
+	linha(3, `<xs:element name="Forma" type="xs:string"/>`)
+	linha(3, `<xs:element name="Quantidade" type="xs:double" minOccurs="0"/>`)
+	linha(3, `<xs:element name="Unidade" type="UnidadeApresentacao"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(1, `</xs:complexType>`)
+	enumeracao("UnidadeApresentacao", []string{"", "UN", "ML", "G"})
+
+	// Catalogs. List elements are always written, even when empty.
+	lista("Laboratorios", "Laboratorio", "Laboratorio", "0")
+	linha(1, `<xs:complexType name="Laboratorio">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Nome" type="xs:string"/>`)
+	linha(3, `<xs:element name="Variantes" type="Variantes"/>`)
+	linha(3, `<xs:element name="Produtos" type="xs:int"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="cnpj" type="xs:string" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+	lista("Variantes", "Variante", "xs:string", "0")
+
+	lista("GruposLaboratorios", "GrupoLaboratorios", "GrupoLaboratorios", "0")
+	linha(1, `<xs:complexType name="GrupoLaboratorios">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="CNPJ" type="xs:string" maxOccurs="unbounded"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="raiz" type="xs:string" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+
+	lista("Apresentacoes", "Apresentacao", "ApresentacaoCatalogo", "0")
+	linha(1, `<xs:complexType name="ApresentacaoCatalogo">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Descricao" type="xs:string"/>`)
+	linha(3, `<xs:element name="Ocorrencias" type="xs:int"/>`)
+	linha(3, `<xs:element name="Variantes" type="Variantes"/>`)
+	linha(3, `<xs:element name="CodigosGGREM" type="CodigosGGREM"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="id" type="xs:int" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+	lista("CodigosGGREM", "CodigoGGREM", "xs:string", "0")
+
+	lista("CatalogoSubstancias", "Substancia", "SubstanciaCatalogo", "0")
+	linha(1, `<xs:complexType name="SubstanciaCatalogo">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="CodigoGGREM" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="nome" type="xs:string" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+
+	lista("ClassesTerapeuticas", "ClasseTerapeutica", "ClasseTerapeutica", "0")
+	linha(1, `<xs:complexType name="ClasseTerapeutica">`)
+	linha(2, `<xs:simpleContent>`)
+	linha(3, `<xs:extension base="xs:string">`)
+	linha(4, `<xs:attribute name="codigo" type="xs:string" use="required"/>`)
+	linha(3, `</xs:extension>`)
+	linha(2, `</xs:simpleContent>`)
+	linha(1, `</xs:complexType>`)
+
+	linha(1, `<xs:complexType name="ResumoTributario">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="PorLista" type="ContagensLista"/>`)
+	linha(3, `<xs:element name="PorLaboratorio" type="ResumoLaboratorios"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(1, `</xs:complexType>`)
+	lista("ContagensLista", "Lista", "ContagemLista", "0")
+	linha(1, `<xs:complexType name="ContagemLista">`)
+	linha(2, `<xs:simpleContent>`)
+	linha(3, `<xs:extension base="xs:int">`)
+	linha(4, `<xs:attribute name="codigo" type="ListaPISCOFINS" use="required"/>`)
+	linha(3, `</xs:extension>`)
+	linha(2, `</xs:simpleContent>`)
+	linha(1, `</xs:complexType>`)
+	lista("ResumoLaboratorios", "Laboratorio", "ResumoLaboratorio", "0")
+	linha(1, `<xs:complexType name="ResumoLaboratorio">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Lista" type="ContagemLista" maxOccurs="unbounded"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="cnpj" type="xs:string" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+
+	lista("GruposEquivalencia", "GrupoEquivalencia", "GrupoEquivalencia", "0")
+	linha(1, `<xs:complexType name="GrupoEquivalencia">`)
+	linha(2, `<xs:sequence>`)
+	linha(3, `<xs:element name="Substancias" type="Substancias"/>`)
+	linha(3, `<xs:element name="Concentracao" type="xs:string"/>`)
+	linha(3, `<xs:element name="Forma" type="xs:string"/>`)
+	linha(3, `<xs:element name="Membros" type="Membros"/>`)
+	for _, campo := range []string{"MaisBarato", "MaisCaro"} {
+		linha(3, `<xs:element name="%s" type="xs:string" minOccurs="0"/>`, campo)
+	}
+	for _, campo := range []string{"PrecoUnitarioMinimo", "PrecoUnitarioMaximo", "AmplitudePercentual"} {
+		linha(3, `<xs:element name="%s" type="xs:double" minOccurs="0"/>`, campo)
+	}
+	linha(2, `</xs:sequence>`)
+	linha(2, `<xs:attribute name="id" type="xs:int" use="required"/>`)
+	linha(1, `</xs:complexType>`)
+	lista("Membros", "Membro", "MembroEquivalencia", "0")
+	linha(1, `<xs:complexType name="MembroEquivalencia">`)
+	linha(2, `<xs:sequence>`)
+	for _, campo := range []string{"CodigoGGREM", "Produto", "Laboratorio", "Apresentacao"} {
+		linha(3, `<xs:element name="%s" type="xs:string"/>`, campo)
+	}
+	linha(3, `<xs:element name="Papel" type="PapelEquivalencia"/>`)
+	linha(3, `<xs:element name="PrecoSemImpostos" type="xs:double" minOccurs="0"/>`)
+	linha(3, `<xs:element name="PrecoUnitario" type="xs:double" minOccurs="0"/>`)
+	linha(2, `</xs:sequence>`)
+	linha(1, `</xs:complexType>`)
+	enumeracao("PapelEquivalencia", []string{string(PapelReferencia), string(PapelGenerico), string(PapelSimilar), string(PapelOutro)})
+
+	linha(0, `</xs:schema>`)
+	return b.String()
+}
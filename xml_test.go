@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xsdSchema is the subset of XML Schema used by geraXSD: global complex and
+// simple types, sequences of local elements, attributes, simple content and
+// enumerations.
+type xsdSchema struct {
+	Elementos []struct {
+		Nome string          `xml:"name,attr"`
+		Tipo xsdTipoComplexo `xml:"complexType"`
+	} `xml:"element"`
+	TiposComplexos []xsdTipoComplexo `xml:"complexType"`
+	TiposSimples   []struct {
+		Nome    string `xml:"name,attr"`
+		Valores []struct {
+			Valor string `xml:"value,attr"`
+		} `xml:"restriction>enumeration"`
+	} `xml:"simpleType"`
+}
+
+type xsdElemento struct {
+	Nome      string `xml:"name,attr"`
+	Tipo      string `xml:"type,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+}
+
+type xsdAtributo struct {
+	Nome string `xml:"name,attr"`
+	Tipo string `xml:"type,attr"`
+	Uso  string `xml:"use,attr"`
+}
+
+type xsdTipoComplexo struct {
+	Nome      string        `xml:"name,attr"`
+	Elementos []xsdElemento `xml:"sequence>element"`
+	Atributos []xsdAtributo `xml:"attribute"`
+	Extensao  *struct {
+		Base      string        `xml:"base,attr"`
+		Atributos []xsdAtributo `xml:"attribute"`
+	} `xml:"simpleContent>extension"`
+}
+
+// noXML is an element of a document being validated.
+type noXML struct {
+	XMLName   xml.Name
+	Atributos []xml.Attr `xml:",any,attr"`
+	Texto     string     `xml:",chardata"`
+	Filhos    []noXML    `xml:",any"`
+}
+
+var decimalXSD = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// validaValorXSD checks a text against a built-in type or an enumeration.
+func (s xsdSchema) validaValorXSD(tipo, valor string) bool {
+	switch tipo {
+	case "xs:string":
+		return true
+	case "xs:int":
+		_, err := strconv.ParseInt(valor, 10, 32)
+		return err == nil
+	case "xs:boolean":
+		return slices.Contains([]string{"true", "false", "1", "0"}, valor)
+	case "xs:decimal":
+		return decimalXSD.MatchString(valor)
+	case "xs:double":
+		_, err := strconv.ParseFloat(valor, 64)
+		return err == nil
+	}
+	for _, simples := range s.TiposSimples {
+		if simples.Nome == tipo {
+			return slices.ContainsFunc(simples.Valores, func(v struct {
+				Valor string `xml:"value,attr"`
+			}) bool {
+				return v.Valor == valor
+			})
+		}
+	}
+	return false
+}
+
+func (s xsdSchema) validaAtributos(no noXML, atributos []xsdAtributo, caminho string) []string {
+	var erros []string
+	for _, atributo := range atributos {
+		i := slices.IndexFunc(no.Atributos, func(a xml.Attr) bool { return a.Name.Local == atributo.Nome })
+		switch {
+		case i < 0 && atributo.Uso == "required":
+			erros = append(erros, fmt.Sprintf("%s: atributo obrigatório %s ausente", caminho, atributo.Nome))
+		case i >= 0 && !s.validaValorXSD(atributo.Tipo, no.Atributos[i].Value):
+			erros = append(erros, fmt.Sprintf("%s: atributo %s=%q não é %s", caminho, atributo.Nome, no.Atributos[i].Value, atributo.Tipo))
+		}
+	}
+	for _, a := range no.Atributos {
+		if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" && !slices.ContainsFunc(atributos, func(x xsdAtributo) bool { return x.Nome == a.Name.Local }) {
+			erros = append(erros, fmt.Sprintf("%s: atributo não permitido %s", caminho, a.Name.Local))
+		}
+	}
+	return erros
+}
+
+// validaXSD checks an element against a type of the schema.
+func (s xsdSchema) validaXSD(no noXML, tipo xsdTipoComplexo, caminho string) []string {
+	if tipo.Extensao != nil {
+		erros := s.validaAtributos(no, tipo.Extensao.Atributos, caminho)
+		if len(no.Filhos) > 0 || !s.validaValorXSD(tipo.Extensao.Base, no.Texto) {
+			erros = append(erros, fmt.Sprintf("%s: conteúdo %q não é %s", caminho, no.Texto, tipo.Extensao.Base))
+		}
+		return erros
+	}
+
+	erros := s.validaAtributos(no, tipo.Atributos, caminho)
+	if strings.TrimSpace(no.Texto) != "" {
+		erros = append(erros, fmt.Sprintf("%s: texto não permitido %q", caminho, no.Texto))
+	}
+	i := 0
+	for _, elemento := range tipo.Elementos {
+		ocorrencias := 0
+		for ; i < len(no.Filhos) && no.Filhos[i].XMLName.Local == elemento.Nome; i++ {
+			ocorrencias++
+			filho, caminhoFilho := no.Filhos[i], caminho+"/"+elemento.Nome
+			if filho.XMLName.Space != xmlNamespace {
+				erros = append(erros, fmt.Sprintf("%s: namespace %q inesperado", caminhoFilho, filho.XMLName.Space))
+			}
+			if complexo, ok := s.tipoComplexo(elemento.Tipo); ok {
+				erros = append(erros, s.validaXSD(filho, complexo, caminhoFilho)...)
+			} else if len(filho.Filhos) > 0 || len(filho.Atributos) > 0 || !s.validaValorXSD(elemento.Tipo, filho.Texto) {
+				erros = append(erros, fmt.Sprintf("%s: conteúdo %q não é %s", caminhoFilho, filho.Texto, elemento.Tipo))
+			}
+		}
+		if minimo := elemento.MinOccurs; ocorrencias < 1 && minimo != "0" {
+			erros = append(erros, fmt.Sprintf("%s: elemento obrigatório %s ausente", caminho, elemento.Nome))
+		}
+		if ocorrencias > 1 && elemento.MaxOccurs != "unbounded" {
+			erros = append(erros, fmt.Sprintf("%s: elemento %s repetido", caminho, elemento.Nome))
+		}
+	}
+	for ; i < len(no.Filhos); i++ {
+		erros = append(erros, fmt.Sprintf("%s: elemento não permitido %s", caminho, no.Filhos[i].XMLName.Local))
+	}
+	return erros
+}
+
+func (s xsdSchema) tipoComplexo(nome string) (xsdTipoComplexo, bool) {
+	i := slices.IndexFunc(s.TiposComplexos, func(tipo xsdTipoComplexo) bool { return tipo.Nome == nome })
+	if i < 0 {
+		return xsdTipoComplexo{}, false
+	}
+	return s.TiposComplexos[i], true
+}
+
+// validaDocumentoXSD checks an XML document against geraXSD.
+func validaDocumentoXSD(t *testing.T, documento []byte) []string {
+	t.Helper()
+	var schema xsdSchema
+	if err := xml.Unmarshal([]byte(geraXSD()), &schema); err != nil {
+		t.Fatalf("failed to decode xsd: %v", err)
+	}
+	var raiz noXML
+	if err := xml.Unmarshal(documento, &raiz); err != nil {
+		t.Fatalf("failed to decode xml: %v", err)
+	}
+	if len(schema.Elementos) != 1 || raiz.XMLName != (xml.Name{Space: xmlNamespace, Local: schema.Elementos[0].Nome}) {
+		return []string{fmt.Sprintf("raiz %v inesperada", raiz.XMLName)}
+	}
+	return schema.validaXSD(raiz, schema.Elementos[0].Tipo, "/"+raiz.XMLName.Local)
+}
+
+func TestXSDArquivo(t *testing.T) {
+	arquivo, err := os.ReadFile("schema/output.xsd")
+	if err != nil {
+		t.Fatalf("failed to read xsd file: %v", err)
+	}
+	if string(arquivo) != geraXSD() {
+		t.Error("schema/output.xsd desatualizado; gere-o novamente com: go run . schema --format xml > schema/output.xsd")
+	}
+}
+
+func TestTiposXSD(t *testing.T) {
+	for _, campo := range camposDerivados {
+		if _, ok := tiposXSD[campo]; !ok && !camposForaXML[campo] {
+			t.Errorf("campo derivado %s sem tipo no XSD", campo)
+		}
+	}
+}
+
+func TestCodificaXML(t *testing.T) {
+	quantidade, preco := 100.0, 12.5
+	output := Output{
+		Metadados: Metadados{Data: "2025-07-03", Observacoes: []string{"Observação 1"}},
+		Medicamentos: []Medicamento{{
+			PrincipioAtivo: "IBUPROFENO", CodigoGGREM: "500101101111417", EAN2: nil, RestricaoHospitalar: true,
+			PFSemImpostos: 12.5, PF175ALC: 15.0, PMVG18: nil,
+			Substancias:          []string{"IBUPROFENO"},
+			TipoProdutoCodigo:    TipoProdutoGenerico,
+			ApresentacaoID:       3,
+			ApresentacaoDetalhes: DetalhesApresentacao{Concentracao: "50 MG/ML", Forma: "SOL OR", Quantidade: &quantidade, Unidade: "ML"},
+			GrupoEquivalenciaID:  nil,
+			PrecosMarcados:       []string{PF175ALC},
+			PrecosUnitarios:      map[string]float64{PFSemImpostos: 0.125, PF175ALC: 0.15},
+			Linha:                4,
+		}},
+		Laboratorios: map[string]CadastroLaboratorio{
+			"98.765.432/0001-10": {Nome: "LAB B", Variantes: []string{"LAB B"}, Produtos: 1},
+			"12.345.678/0001-90": {Nome: "LAB A", Variantes: []string{"LAB A", "LAB A S.A."}, Produtos: 2},
+		},
+		GruposLaboratorios:  map[string][]string{"12.345.678": {"12.345.678/0001-90"}},
+		Apresentacoes:       []ApresentacaoCatalogo{{ID: 3, Descricao: "50 MG/ML SOL OR", Ocorrencias: 1, Variantes: []string{"50 MG/ML SOL OR"}, CodigosGGREM: []string{"500101101111417"}}},
+		Substancias:         map[string][]string{"IBUPROFENO": {"500101101111417"}},
+		ClassesTerapeuticas: map[string]string{"M1A": "ANTI-REUMÁTICOS"},
+		ResumoTributario: ResumoTributario{
+			PorLista:       map[ListaPISCOFINS]int{ListaPositiva: 1, ListaNegativa: 2},
+			PorLaboratorio: map[string]map[ListaPISCOFINS]int{"12.345.678/0001-90": {ListaPositiva: 1}},
+		},
+		GruposEquivalencia: []GrupoEquivalencia{{
+			ID: 1, Substancias: []string{"IBUPROFENO"}, Concentracao: "50 MG/ML", Forma: "SOL OR",
+			Membros: []MembroEquivalencia{
+				{CodigoGGREM: "500101101111417", Papel: PapelGenerico, PrecoSemImpostos: &preco},
+				{CodigoGGREM: "2", Papel: PapelOutro},
+			},
+		}},
+		Avisos: []string{"aviso <1>"},
+	}
+
+	var buf bytes.Buffer
+	if err := codificaXML(&buf, output, OpcoesSaida{}); err != nil {
+		t.Fatalf("codificaXML failed: %v", err)
+	}
+	for _, erro := range validaDocumentoXSD(t, buf.Bytes()) {
+		t.Error(erro)
+	}
+
+	type precoDecodificado struct {
+		Coluna   string `xml:"coluna,attr"`
+		Tipo     string `xml:"tipo,attr"`
+		ICMS     string `xml:"icms,attr"`
+		ALC      bool   `xml:"alc,attr"`
+		Valor    string `xml:"valor,attr"`
+		Unitario string `xml:"unitario,attr"`
+		Marcado  bool   `xml:"marcado,attr"`
+	}
+	var documento struct {
+		XMLName      xml.Name
+		VersaoSchema string    `xml:"versaoSchema,attr"`
+		Metadados    Metadados `xml:"Metadados"`
+		Medicamentos []struct {
+			Linha               int    `xml:"linha,attr"`
+			PrincipioAtivo      string `xml:"PrincipioAtivo"`
+			CodigoGGREM         string `xml:"CodigoGGREM"`
+			EAN2                *string
+			Restricao           bool                 `xml:"RestricaoHospitalar"`
+			Precos              []precoDecodificado  `xml:"Precos>Preco"`
+			Substancias         []string             `xml:"Substancias>Substancia"`
+			TipoProduto         string               `xml:"TipoProdutoCodigo"`
+			ApresentacaoID      int                  `xml:"ApresentacaoID"`
+			Detalhes            DetalhesApresentacao `xml:"ApresentacaoDetalhes"`
+			GrupoEquivalenciaID *int
+		} `xml:"Medicamentos>Medicamento"`
+		Laboratorios        []laboratorioXML       `xml:"Laboratorios>Laboratorio"`
+		GruposLaboratorios  []grupoLaboratoriosXML `xml:"GruposLaboratorios>GrupoLaboratorios"`
+		Apresentacoes       []ApresentacaoCatalogo `xml:"Apresentacoes>Apresentacao"`
+		Substancias         []substanciaXML        `xml:"Substancias>Substancia"`
+		ClassesTerapeuticas []classeTerapeuticaXML `xml:"ClassesTerapeuticas>ClasseTerapeutica"`
+		ResumoTributario    resumoTributarioXML    `xml:"ResumoTributario"`
+		GruposEquivalencia  []GrupoEquivalencia    `xml:"GruposEquivalencia>GrupoEquivalencia"`
+		Avisos              []string               `xml:"Avisos>Aviso"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &documento); err != nil {
+		t.Fatalf("failed to decode xml: %v\n%s", err, buf.String())
+	}
+
+	if expected := (xml.Name{Space: xmlNamespace, Local: "TabelaCMED"}); documento.XMLName != expected || documento.VersaoSchema != versaoSchema {
+		t.Errorf("esperado: %v %s, obtido: %v %s", expected, versaoSchema, documento.XMLName, documento.VersaoSchema)
+	}
+	if documento.Metadados.Data != "2025-07-03" || !slices.Equal(documento.Metadados.Observacoes, []string{"Observação 1"}) {
+		t.Errorf("esperado: %+v, obtido: %+v", output.Metadados, documento.Metadados)
+	}
+	if len(documento.Medicamentos) != 1 {
+		t.Fatalf("esperado: 1 medicamento, obtido: %d", len(documento.Medicamentos))
+	}
+
+	m := documento.Medicamentos[0]
+	if m.Linha != 4 || m.PrincipioAtivo != "IBUPROFENO" || m.CodigoGGREM != "500101101111417" || !m.Restricao {
+		t.Errorf("esperado: linha 4 de IBUPROFENO 500101101111417 com restrição, obtido: %+v", m)
+	}
+	if m.EAN2 != nil || m.GrupoEquivalenciaID != nil {
+		t.Errorf("esperado: campos nulos omitidos, obtido: %v %v", m.EAN2, m.GrupoEquivalenciaID)
+	}
+	expectedPrecos := []precoDecodificado{
+		{Coluna: "PFSemImpostos", Tipo: "PF", Valor: "12.5", Unitario: "0.125"},
+		{Coluna: "PF175ALC", Tipo: "PF", ICMS: "17.5", ALC: true, Valor: "15", Unitario: "0.15", Marcado: true},
+	}
+	if !slices.Equal(m.Precos, expectedPrecos) {
+		t.Errorf("esperado: %+v, obtido: %+v", expectedPrecos, m.Precos)
+	}
+	if !slices.Equal(m.Substancias, []string{"IBUPROFENO"}) || m.TipoProduto != "GENERICO" || m.ApresentacaoID != 3 {
+		t.Errorf("esperado: IBUPROFENO GENERICO na apresentação 3, obtido: %+v", m)
+	}
+	if m.Detalhes.Forma != "SOL OR" || m.Detalhes.Quantidade == nil || *m.Detalhes.Quantidade != 100 || m.Detalhes.Unidade != "ML" {
+		t.Errorf("esperado: %+v, obtido: %+v", output.Medicamentos[0][ApresentacaoDetalhes], m.Detalhes)
+	}
+
+	testCases := []struct {
+		name     string
+		expected any
+		result   any
+	}{
+		{"laboratórios em ordem de CNPJ", []string{"12.345.678/0001-90", "98.765.432/0001-10"}, []string{documento.Laboratorios[0].CNPJ, documento.Laboratorios[1].CNPJ}},
+		{"variantes do laboratório", []string{"LAB A", "LAB A S.A."}, documento.Laboratorios[0].Variantes},
+		{"grupos de laboratórios", []grupoLaboratoriosXML{{"12.345.678", []string{"12.345.678/0001-90"}}}, documento.GruposLaboratorios},
+		{"apresentações", output.Apresentacoes, documento.Apresentacoes},
+		{"substâncias", []substanciaXML{{"IBUPROFENO", []string{"500101101111417"}}}, documento.Substancias},
+		{"classes terapêuticas", []classeTerapeuticaXML{{"M1A", "ANTI-REUMÁTICOS"}}, documento.ClassesTerapeuticas},
+		{"resumo por lista", []contagemListaXML{{ListaNegativa, 2}, {ListaPositiva, 1}}, documento.ResumoTributario.PorLista},
+		{"resumo por laboratório", []resumoLaboratorioXML{{"12.345.678/0001-90", []contagemListaXML{{ListaPositiva, 1}}}}, documento.ResumoTributario.PorLaboratorio},
+		{"membros do grupo", []string{"500101101111417", "2"}, []string{documento.GruposEquivalencia[0].Membros[0].CodigoGGREM, documento.GruposEquivalencia[0].Membros[1].CodigoGGREM}},
+		{"preço do membro", 12.5, *documento.GruposEquivalencia[0].Membros[0].PrecoSemImpostos},
+		{"avisos", output.Avisos, documento.Avisos},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if fmt.Sprint(tc.result) != fmt.Sprint(tc.expected) {
+				t.Errorf("esperado: %v, obtido: %v", tc.expected, tc.result)
+			}
+		})
+	}
+}
+
+func TestEscreveSaidaXMLValidaXSD(t *testing.T) {
+	rows := linhasSinteticas(30)
+	// Two equivalent presentations, so that an equivalence group exists.
+	rows[2+1][0], rows[2+1][9] = rows[2][0], rows[2][9]
+	rows[2+5][13] = "10,00*"
+	rows[2+7][72] = "Tarja Roxa"
+	data := time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)
+	output, err := processRows(rows, data, data, OpcoesProcessamento{})
+	if err != nil {
+		t.Fatalf("processRows failed: %v", err)
+	}
+	if len(output.GruposEquivalencia) == 0 || len(output.Avisos) == 0 {
+		t.Fatalf("esperado: grupos de equivalência e avisos, obtido: %d e %d", len(output.GruposEquivalencia), len(output.Avisos))
+	}
+
+	testCases := []struct {
+		name   string
+		output Output
+		opcoes OpcoesSaida
+	}{
+		{"completo", output, OpcoesSaida{Formato: FormatoXML, Canonico: true}},
+		{"colunas selecionadas", output, OpcoesSaida{Formato: FormatoXML, Projecao: Projecao{Colunas: []string{CodigoGGREM, PF18, Substancias}, OmitirNulos: true}}},
+		{"vazio", Output{Metadados: Metadados{Data: "2025-07-03"}}, OpcoesSaida{Formato: FormatoXML}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			infilePath := filepath.Join(t.TempDir(), "lista.xlsx")
+			if err := escreveSaida(tc.output, infilePath, tc.opcoes); err != nil {
+				t.Fatalf("escreveSaida failed: %v", err)
+			}
+			conteudo, err := os.ReadFile(filepath.Join(filepath.Dir(infilePath), "lista.xml"))
+			if err != nil {
+				t.Fatalf("failed to read xml file: %v", err)
+			}
+			if !bytes.HasPrefix(conteudo, []byte(xml.Header)) {
+				t.Errorf("esperado: cabeçalho XML, obtido: %.40s", conteudo)
+			}
+			for _, erro := range validaDocumentoXSD(t, conteudo) {
+				t.Error(erro)
+			}
+		})
+	}
+}